@@ -0,0 +1,400 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/common"
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/hub"
+	"source.toby3d.me/toby3d/hub/internal/publisher"
+	"source.toby3d.me/toby3d/hub/internal/subscription"
+	topicpkg "source.toby3d.me/toby3d/hub/internal/topic"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+type (
+	NewHandlerParams struct {
+		Hub        hub.UseCase
+		Topics     topicpkg.UseCase
+		Publishers publisher.UseCase
+		// Bearer token of administrator, all requests will be rejected
+		// if it's empty.
+		Token string
+		// Sort query parameters of requested topic URLs.
+		SortQuery bool
+	}
+
+	// Handler serves administrative actions under the /admin/ path.
+	Handler struct {
+		hub        hub.UseCase
+		topics     topicpkg.UseCase
+		publishers publisher.UseCase
+		token      string
+		sortQuery  bool
+	}
+)
+
+// Form parameters of polling interval override, publisher identifier and it's
+// topic URL prefixes, and number of scheduled replays.
+const (
+	paramInterval string = "interval"
+	paramID       string = "id"
+	paramPrefix   string = "prefix"
+	paramToken    string = "token"
+	paramSecret   string = "secret"
+	paramCount    string = "count"
+)
+
+func NewHandler(params NewHandlerParams) *Handler {
+	return &Handler{
+		hub:        params.Hub,
+		topics:     params.Topics,
+		publishers: params.Publishers,
+		token:      params.Token,
+		sortQuery:  params.SortQuery,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token == "" {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if !h.authorize(r) {
+		w.Header().Set(common.HeaderWWWAuthenticate, `Bearer realm="admin"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+		return
+	}
+
+	// NOTE(toby3d): skip the "admin" prefix.
+	_, tail := urlutil.ShiftPath(r.URL.Path)
+	head, _ := urlutil.ShiftPath(tail)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	switch head {
+	default:
+		http.NotFound(w, r)
+	case "revoke":
+		h.handleRevoke(w, r)
+	case "retire":
+		h.handleRetire(w, r)
+	case "replay":
+		h.handleReplay(w, r)
+	case "schedule":
+		h.handleSchedule(w, r)
+	case "register":
+		h.handleRegister(w, r)
+	case "unregister":
+		h.handleUnregister(w, r)
+	}
+}
+
+// handleRevoke removes hub.callback subscription to hub.topic and notifies
+// subscriber about it with optional hub.reason.
+func (h *Handler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	topic, err := h.parseTopic(r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	callback, err := parseURL(r.PostForm, common.HubCallback)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	reason := error(domain.ErrReasonRevoked)
+	if r.PostForm.Has(common.HubReason) {
+		reason = errors.New(r.PostForm.Get(common.HubReason))
+	}
+
+	ok, err := h.hub.Revoke(r.Context(), domain.NewSSID(domain.Topic{Self: topic}, callback), reason)
+	if err != nil && !ok {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRetire revokes all subscriptions to hub.topic with optional hub.reason
+// and purges it's content.
+func (h *Handler) handleRetire(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	topic, err := h.parseTopic(r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	reason := error(domain.ErrReasonRetired)
+	if r.PostForm.Has(common.HubReason) {
+		reason = errors.New(r.PostForm.Get(common.HubReason))
+	}
+
+	ok, err := h.hub.Retire(r.Context(), topic, reason)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReplay delivers the current or requested hub.version of hub.topic
+// content once again to hub.callback subscriber, or to every topic subscriber
+// if it's omitted, and responds with the number of scheduled deliveries.
+func (h *Handler) handleReplay(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	topic, err := h.parseTopic(r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var callback *url.URL
+	if r.PostForm.Has(common.HubCallback) {
+		if callback, err = parseURL(r.PostForm, common.HubCallback); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	var version uint64
+	if r.PostForm.Has(common.HubVersion) {
+		if version, err = strconv.ParseUint(r.PostForm.Get(common.HubVersion), 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("%s MUST be a positive integer, got %q", common.HubVersion,
+				r.PostForm.Get(common.HubVersion)), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	count, err := h.hub.Replay(r.Context(), topic, callback, version)
+	if err != nil {
+		if errors.Is(err, topicpkg.ErrNotExist) || errors.Is(err, topicpkg.ErrVersionNotExist) ||
+			errors.Is(err, subscription.ErrNotExist) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprint(w, url.Values{paramCount: {strconv.Itoa(count)}}.Encode())
+}
+
+// handleSchedule overrides the polling interval of hub.topic by interval
+// duration, such as "90m". Zero interval restores the adaptive polling.
+func (h *Handler) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	topic, err := h.parseTopic(r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	interval, err := time.ParseDuration(r.PostForm.Get(paramInterval))
+	if err != nil || interval < 0 {
+		http.Error(w, fmt.Sprintf("%s MUST be a non-negative duration, got %q", paramInterval,
+			r.PostForm.Get(paramInterval)), http.StatusBadRequest)
+
+		return
+	}
+
+	ok, err := h.topics.Schedule(r.Context(), topic, interval)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRegister creates a new trusted publisher of topics under the one or more
+// prefix URLs and responds with it's id, bearer token and signature secret.
+func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
+	if h.publishers == nil {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if len(r.PostForm[paramPrefix]) == 0 {
+		http.Error(w, fmt.Sprintf("%s parameter is required, but not provided", paramPrefix),
+			http.StatusBadRequest)
+
+		return
+	}
+
+	prefixes := make([]*url.URL, 0, len(r.PostForm[paramPrefix]))
+
+	for _, v := range r.PostForm[paramPrefix] {
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			http.Error(w, fmt.Sprintf("%s MUST contain scheme and host: %s", paramPrefix, v),
+				http.StatusBadRequest)
+
+			return
+		}
+
+		prefixes = append(prefixes, u)
+	}
+
+	p, token, err := h.publishers.Register(r.Context(), prefixes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, url.Values{
+		paramID:     {p.ID},
+		paramToken:  {token},
+		paramSecret: {p.Secret.String()},
+	}.Encode())
+}
+
+// handleUnregister removes trusted publisher by it's id.
+func (h *Handler) handleUnregister(w http.ResponseWriter, r *http.Request) {
+	if h.publishers == nil {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	ok, err := h.publishers.Unregister(r.Context(), r.PostForm.Get(paramID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) authorize(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get(common.HeaderAuthorization), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// parseTopic parses topic URL of form, with sorted query parameters if the hub
+// is configured so.
+func (h *Handler) parseTopic(form url.Values) (*url.URL, error) {
+	u, err := parseURL(form, common.HubTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.sortQuery {
+		u = urlutil.SortQuery(u)
+	}
+
+	return u, nil
+}
+
+func parseURL(form url.Values, key string) (*url.URL, error) {
+	if !form.Has(key) {
+		return nil, fmt.Errorf("%s parameter is required, but not provided", key)
+	}
+
+	u, err := url.Parse(form.Get(key))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+
+	return urlutil.Canonical(u), nil
+}
@@ -0,0 +1,333 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	delivery "source.toby3d.me/toby3d/hub/internal/admin/delivery/http"
+	"source.toby3d.me/toby3d/hub/internal/common"
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	hubucase "source.toby3d.me/toby3d/hub/internal/hub/usecase"
+	publishermemoryrepo "source.toby3d.me/toby3d/hub/internal/publisher/repository/memory"
+	publisherucase "source.toby3d.me/toby3d/hub/internal/publisher/usecase"
+	queuememoryrepo "source.toby3d.me/toby3d/hub/internal/queue/repository/memory"
+	subscriptionmemoryrepo "source.toby3d.me/toby3d/hub/internal/subscription/repository/memory"
+	topicmemoryrepo "source.toby3d.me/toby3d/hub/internal/topic/repository/memory"
+)
+
+func TestHandler_ServeHTTP_Revoke(t *testing.T) {
+	t.Parallel()
+
+	denied := make(chan url.Values, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		denied <- r.URL.Query()
+	}))
+	t.Cleanup(srv.Close)
+
+	in := domain.TestSubscription(t, srv.URL+"/lipsum")
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+
+	if err := subscriptions.Create(context.Background(), in.SUID(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	config := domain.TestConfig(t)
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topicmemoryrepo.NewMemoryTopicRepository(),
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        srv.Client(),
+			Config:        config,
+		}),
+		Token: config.AdminToken,
+	})
+
+	payload := make(url.Values)
+	payload.Set(common.HubTopic, in.Topic.String())
+	payload.Set(common.HubCallback, in.Callback.String())
+
+	// NOTE(toby3d): cases depends from each other, so run them in order.
+	for _, tc := range []struct {
+		token  string
+		expect int
+	}{
+		{token: "", expect: http.StatusUnauthorized},
+		{token: config.AdminToken, expect: http.StatusNoContent},
+		{token: config.AdminToken, expect: http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/admin/revoke",
+			strings.NewReader(payload.Encode()))
+		req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+		if tc.token != "" {
+			req.Header.Set(common.HeaderAuthorization, "Bearer "+tc.token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if resp := w.Result(); resp.StatusCode != tc.expect {
+			t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, tc.expect)
+		}
+	}
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not notified about revoked subscription")
+	case q := <-denied:
+		if actual := q.Get(common.HubMode); actual != domain.ModeDenied.String() {
+			t.Errorf("want '%s', got '%s'", domain.ModeDenied, actual)
+		}
+
+		if actual := q.Get(common.HubReason); actual != domain.ErrReasonRevoked.Error() {
+			t.Errorf("want '%s', got '%s'", domain.ErrReasonRevoked, actual)
+		}
+	}
+}
+
+func TestHandler_ServeHTTP_Retire(t *testing.T) {
+	t.Parallel()
+
+	denied := make(chan url.Values, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		denied <- r.URL.Query()
+	}))
+	t.Cleanup(srv.Close)
+
+	in := domain.TestSubscription(t, srv.URL+"/lipsum")
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+
+	if err := subscriptions.Create(context.Background(), in.SUID(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	topic := domain.TestTopic(t)
+	topic.Self = in.Topic
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	config := domain.TestConfig(t)
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        srv.Client(),
+			Config:        config,
+		}),
+		Token: config.AdminToken,
+	})
+
+	payload := make(url.Values)
+	payload.Set(common.HubTopic, in.Topic.String())
+
+	// NOTE(toby3d): cases depends from each other, so run them in order.
+	for _, expect := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/admin/retire",
+			strings.NewReader(payload.Encode()))
+		req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+		req.Header.Set(common.HeaderAuthorization, "Bearer "+config.AdminToken)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if resp := w.Result(); resp.StatusCode != expect {
+			t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
+		}
+	}
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not notified about retired topic")
+	case q := <-denied:
+		if actual := q.Get(common.HubReason); actual != domain.ErrReasonRetired.Error() {
+			t.Errorf("want '%s', got '%s'", domain.ErrReasonRetired, actual)
+		}
+	}
+
+	if _, err := subscriptions.Get(context.Background(), in.SUID()); err == nil {
+		t.Error("want removed subscription of retired topic, got exists")
+	}
+}
+
+func TestHandler_ServeHTTP_Register(t *testing.T) {
+	t.Parallel()
+
+	config := domain.TestConfig(t)
+	publishers := publisherucase.NewPublisherUseCase(publisherucase.NewPublisherUseCaseParams{
+		Publishers: publishermemoryrepo.NewMemoryPublisherRepository(),
+	})
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Publishers: publishers,
+		Token:      config.AdminToken,
+	})
+
+	payload := make(url.Values)
+	payload.Add("prefix", "https://example.com/blog/")
+
+	req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/admin/register",
+		strings.NewReader(payload.Encode()))
+	req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+	req.Header.Set(common.HeaderAuthorization, "Bearer "+config.AdminToken)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, http.StatusCreated)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	credentials, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := publishers.Authenticate(context.Background(), credentials.Get("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.ID != credentials.Get("id") {
+		t.Errorf("want '%s', got '%s'", credentials.Get("id"), p.ID)
+	}
+
+	// NOTE(toby3d): unregister registered publisher twice.
+	for _, expect := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req = httptest.NewRequest(http.MethodPost, "https://hub.example.com/admin/unregister",
+			strings.NewReader(url.Values{"id": {p.ID}}.Encode()))
+		req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+		req.Header.Set(common.HeaderAuthorization, "Bearer "+config.AdminToken)
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if resp = w.Result(); resp.StatusCode != expect {
+			t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
+		}
+	}
+}
+
+func TestHandler_ServeHTTP_Replay(t *testing.T) {
+	t.Parallel()
+
+	in := domain.TestSubscription(t, "https://example.net/callback")
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+
+	if err := subscriptions.Create(context.Background(), in.SUID(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	topic := domain.TestTopic(t)
+	topic.Self = in.Topic
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := queuememoryrepo.NewMemoryQueueRepository()
+	config := domain.TestConfig(t)
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         jobs,
+			Client:        http.DefaultClient,
+			Config:        config,
+			Versions:      topicmemoryrepo.NewMemoryVersionRepository(),
+		}),
+		Token: config.AdminToken,
+	})
+
+	// NOTE(toby3d): cleanup runs after all parallel cases.
+	t.Cleanup(func() {
+		j, err := jobs.Get(context.Background(), in.SUID())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !j.Replay || j.VersionID != topic.VersionID {
+			t.Errorf("want replay of %d version, got %+v", topic.VersionID, j)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		input  url.Values
+		expect int
+	}{
+		"topic": {
+			input:  url.Values{common.HubTopic: {in.Topic.String()}},
+			expect: http.StatusAccepted,
+		},
+		"subscription": {
+			input: url.Values{
+				common.HubTopic:    {in.Topic.String()},
+				common.HubCallback: {in.Callback.String()},
+				common.HubVersion:  {"1"},
+			},
+			expect: http.StatusAccepted,
+		},
+		"unknown version": {
+			input:  url.Values{common.HubTopic: {in.Topic.String()}, common.HubVersion: {"2"}},
+			expect: http.StatusNotFound,
+		},
+		"unknown callback": {
+			input: url.Values{
+				common.HubTopic:    {in.Topic.String()},
+				common.HubCallback: {"https://example.org/callback"},
+			},
+			expect: http.StatusNotFound,
+		},
+		"invalid version": {
+			input:  url.Values{common.HubTopic: {in.Topic.String()}, common.HubVersion: {"latest"}},
+			expect: http.StatusBadRequest,
+		},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/admin/replay",
+				strings.NewReader(tc.input.Encode()))
+			req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+			req.Header.Set(common.HeaderAuthorization, "Bearer "+config.AdminToken)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			if resp.StatusCode != tc.expect {
+				t.Fatalf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, tc.expect)
+			}
+
+			if tc.expect != http.StatusAccepted {
+				return
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+
+			result, err := url.ParseQuery(string(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual := result.Get("count"); actual != "1" {
+				t.Errorf("want %s replays, got %s", "1", actual)
+			}
+		})
+	}
+}
@@ -10,21 +10,34 @@ const (
 )
 
 const (
-	HeaderAcceptLanguage string = "Accept-Language"
-	HeaderContentType    string = "Content-Type"
-	HeaderLink           string = "Link"
-	HeaderXHubSignature  string = "X-Hub-Signature"
+	HeaderAcceptLanguage  string = "Accept-Language"
+	HeaderAuthorization   string = "Authorization"
+	HeaderCacheControl    string = "Cache-Control"
+	HeaderContentType     string = "Content-Type"
+	HeaderETag            string = "ETag"
+	HeaderExpires         string = "Expires"
+	HeaderIfModifiedSince string = "If-Modified-Since"
+	HeaderIfNoneMatch     string = "If-None-Match"
+	HeaderLastModified    string = "Last-Modified"
+	HeaderLink            string = "Link"
+	HeaderRetryAfter      string = "Retry-After"
+	HeaderWWWAuthenticate string = "WWW-Authenticate"
+	HeaderXHubPublisher   string = "X-Hub-Publisher"
+	HeaderXHubSignature   string = "X-Hub-Signature"
+	HeaderXHubTimestamp   string = "X-Hub-Timestamp"
 )
 
 const (
 	HubCallback     string = hub + ".callback"
 	HubChallenge    string = hub + ".challenge"
+	HubContent      string = hub + ".content"
 	HubLeaseSeconds string = hub + ".lease_seconds"
 	HubMode         string = hub + ".mode"
 	HubReason       string = hub + ".reason"
 	HubSecret       string = hub + ".secret"
 	HubTopic        string = hub + ".topic"
 	HubURL          string = hub + ".url"
+	HubVersion      string = hub + ".version"
 )
 
 const Und string = "und"
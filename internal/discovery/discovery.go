@@ -0,0 +1,273 @@
+// Package discovery extracts hub and self links advertised by topic from HTTP
+// Link headers, HTML <link> elements and Atom/RSS atom:link elements.
+//
+// See: https://www.w3.org/TR/websub/#discovery
+package discovery
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"source.toby3d.me/toby3d/hub/internal/common"
+)
+
+// Links contains discovered links of topic.
+type Links struct {
+	// Canonical URL of topic, if any
+	Self *url.URL
+	Hubs []*url.URL
+}
+
+const (
+	relHub  string = "hub"
+	relSelf string = "self"
+
+	namespaceAtom string = "http://www.w3.org/2005/Atom"
+)
+
+// Discover returns links advertised by topic response. Links in the header
+// take precedence over links in the content. Relative links are resolved
+// against base.
+func Discover(base *url.URL, header http.Header, content []byte) Links {
+	out := Links{Hubs: make([]*url.URL, 0)}
+
+	for _, value := range header.Values(common.HeaderLink) {
+		for _, link := range parseLinkHeader(value) {
+			out.add(base, link.href, link.rel)
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get(common.HeaderContentType))
+
+	switch {
+	case mediaType == common.MIMETextHTML, mediaType == "application/xhtml+xml":
+		for _, link := range parseHTML(content) {
+			out.add(base, link.href, link.rel)
+		}
+	case strings.HasSuffix(mediaType, "xml"):
+		for _, link := range parseXML(content) {
+			out.add(base, link.href, link.rel)
+		}
+	}
+
+	return out
+}
+
+// Advertises reports whether hub is listed in discovered hub links.
+func (l Links) Advertises(hub *url.URL) bool {
+	for i := range l.Hubs {
+		if strings.TrimSuffix(l.Hubs[i].String(), "/") == strings.TrimSuffix(hub.String(), "/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+type link struct {
+	href string
+	rel  string
+}
+
+func (l *Links) add(base *url.URL, href, rel string) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return
+	}
+
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case relHub:
+			l.Hubs = append(l.Hubs, u)
+		case relSelf:
+			if l.Self == nil {
+				l.Self = u
+			}
+		}
+	}
+}
+
+// parseLinkHeader parses Link header value, such as
+// `<https://hub.example.com/>; rel="hub", <https://example.com/>; rel="self"`.
+//
+// See: https://www.rfc-editor.org/rfc/rfc8288#section-3
+func parseLinkHeader(value string) []link {
+	out := make([]link, 0)
+
+	for value != "" {
+		start := strings.IndexByte(value, '<')
+		end := strings.IndexByte(value, '>')
+
+		if start < 0 || end < start {
+			break
+		}
+
+		l := link{href: value[start+1 : end]}
+		value = value[end+1:]
+
+		// NOTE(toby3d): parameters of this link ends with the next
+		// link, commas inside quoted values are not expected here.
+		params := value
+		if next := strings.IndexByte(value, '<'); next >= 0 {
+			params, value = value[:next], value[next:]
+		} else {
+			value = ""
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(k), "rel") {
+				continue
+			}
+
+			l.rel = strings.Trim(strings.TrimSpace(strings.TrimRight(strings.TrimSpace(v), ",")), `"`)
+		}
+
+		out = append(out, l)
+	}
+
+	return out
+}
+
+// parseHTML returns <link> elements of HTML document. It's a lightweight
+// scanner instead of a full HTML parser, so it does not skip elements inside
+// comments or scripts.
+func parseHTML(content []byte) []link {
+	out := make([]link, 0)
+	lower := bytes.ToLower(content)
+
+	for {
+		i := bytes.Index(lower, []byte("<link"))
+		if i < 0 {
+			break
+		}
+
+		lower, content = lower[i+len("<link"):], content[i+len("<link"):]
+
+		end := bytes.IndexByte(content, '>')
+		if end < 0 {
+			break
+		}
+
+		attrs := parseAttributes(string(content[:end]))
+		out = append(out, link{href: attrs["href"], rel: attrs["rel"]})
+	}
+
+	return out
+}
+
+// parseAttributes parses attributes of HTML tag, such as
+// `rel="hub" href='https://hub.example.com/'`.
+func parseAttributes(s string) map[string]string {
+	out := make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " \t\r\n/")
+		if s == "" {
+			return out
+		}
+
+		i := strings.IndexAny(s, "= \t\r\n")
+		if i < 0 {
+			out[strings.ToLower(s)] = ""
+
+			return out
+		}
+
+		key := strings.ToLower(s[:i])
+		s = strings.TrimLeft(s[i:], " \t\r\n")
+
+		if !strings.HasPrefix(s, "=") {
+			out[key] = ""
+
+			continue
+		}
+
+		s = strings.TrimLeft(s[1:], " \t\r\n")
+
+		var value string
+
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t\r\n")
+			if end < 0 {
+				end = len(s)
+			}
+
+			value, s = s[:end], s[end:]
+		}
+
+		out[key] = value
+	}
+}
+
+// parseXML returns atom:link elements of Atom feed or RSS channel.
+func parseXML(content []byte) []link {
+	out := make([]link, 0)
+
+	d := xml.NewDecoder(bytes.NewReader(content))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	depth := 0
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return out
+			}
+
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			depth++
+
+			// NOTE(toby3d): links of the feed itself are children of
+			// feed in Atom and of rss/channel in RSS documents, so
+			// there is no reason to read entries.
+			if t.Name.Local == "entry" || t.Name.Local == "item" {
+				return out
+			}
+
+			if t.Name.Local != "link" || t.Name.Space != namespaceAtom || depth > 3 {
+				continue
+			}
+
+			l := link{}
+
+			for _, attr := range t.Attr {
+				switch attr.Name.Local {
+				case "href":
+					l.href = attr.Value
+				case "rel":
+					l.rel = attr.Value
+				}
+			}
+
+			out = append(out, l)
+		}
+	}
+
+	return out
+}
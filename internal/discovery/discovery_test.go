@@ -0,0 +1,68 @@
+package discovery_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"source.toby3d.me/toby3d/hub/internal/common"
+	"source.toby3d.me/toby3d/hub/internal/discovery"
+)
+
+func TestDiscover(t *testing.T) {
+	t.Parallel()
+
+	base := &url.URL{Scheme: "https", Host: "example.com", Path: "/blog/"}
+
+	for name, tc := range map[string]struct {
+		header  http.Header
+		content string
+	}{
+		"header": {
+			header: http.Header{common.HeaderLink: {
+				`<https://hub.example.com/>; rel="hub", </blog/feed>; rel="self"`,
+			}},
+		},
+		"html": {
+			header: http.Header{common.HeaderContentType: {common.MIMETextHTMLCharsetUTF8}},
+			content: `<!DOCTYPE html><html><head>
+				<LINK rel=hub href="https://hub.example.com/">
+				<link href='feed' rel='self alternate' />
+			</head></html>`,
+		},
+		"atom": {
+			header: http.Header{common.HeaderContentType: {"application/atom+xml"}},
+			content: `<?xml version="1.0" encoding="utf-8"?>
+			<feed xmlns="http://www.w3.org/2005/Atom">
+				<link rel="hub" href="https://hub.example.com/"/>
+				<link rel="self" href="https://example.com/blog/feed"/>
+				<entry><link rel="self" href="https://example.com/blog/1"/></entry>
+			</feed>`,
+		},
+		"rss": {
+			header: http.Header{common.HeaderContentType: {"application/rss+xml"}},
+			content: `<?xml version="1.0" encoding="utf-8"?>
+			<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+				<atom:link rel="hub" href="https://hub.example.com/"/>
+				<atom:link rel="self" href="https://example.com/blog/feed"/>
+				<link>https://example.com/blog/</link>
+			</channel></rss>`,
+		},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			links := discovery.Discover(base, tc.header, []byte(tc.content))
+
+			if !links.Advertises(&url.URL{Scheme: "https", Host: "hub.example.com"}) {
+				t.Errorf("want advertised hub, got %v", links.Hubs)
+			}
+
+			if links.Self == nil || links.Self.String() != "https://example.com/blog/feed" {
+				t.Errorf("want self link %s, got %v", "https://example.com/blog/feed", links.Self)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"source.toby3d.me/toby3d/hub/internal/common"
+)
+
+// CircuitState is a state of subscription circuit breaker which stops content
+// distribution to callbacks which repeatedly fail.
+type CircuitState struct {
+	state string
+}
+
+var (
+	CircuitStateUnd      CircuitState = CircuitState{state: ""}          // "und"
+	CircuitStateClosed   CircuitState = CircuitState{state: "closed"}    // "closed"
+	CircuitStateOpen     CircuitState = CircuitState{state: "open"}      // "open"
+	CircuitStateHalfOpen CircuitState = CircuitState{state: "half-open"} // "half-open"
+)
+
+var ErrCircuitStateSyntax = errors.New("bad circuit state syntax")
+
+var stringsCircuitStates = map[string]CircuitState{
+	CircuitStateClosed.state:   CircuitStateClosed,
+	CircuitStateOpen.state:     CircuitStateOpen,
+	CircuitStateHalfOpen.state: CircuitStateHalfOpen,
+}
+
+func ParseCircuitState(state string) (CircuitState, error) {
+	if s, ok := stringsCircuitStates[state]; ok {
+		return s, nil
+	}
+
+	return CircuitStateUnd, fmt.Errorf("%w: %s", ErrCircuitStateSyntax, state)
+}
+
+func (cs CircuitState) String() string {
+	if cs.state != "" {
+		return cs.state
+	}
+
+	return common.Und
+}
+
+func (cs CircuitState) GoString() string {
+	return "domain.CircuitState(" + cs.String() + ")"
+}
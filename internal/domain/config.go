@@ -3,6 +3,7 @@ package domain
 import (
 	"net/url"
 	"testing"
+	"time"
 )
 
 type Config struct {
@@ -10,6 +11,109 @@ type Config struct {
 	Bind    string   `end:"BIND,required" envDefault:":3000"`
 	Name    string   `env:"NAME" envDefault:"WebSub"`
 	DB      string   `env:"DB" envDefault:"./data.db"`
+
+	// Networks in CIDR notation or single addresses which outbound requests
+	// are allowed to, even if they are private, loopback or reserved.
+	AllowNetworks []string `env:"ALLOW_NETWORKS" envSeparator:","`
+
+	// Bearer token for administrative endpoints, which are disabled if
+	// it's empty.
+	AdminToken string `env:"ADMIN_TOKEN"`
+
+	// Bearer token of publishers which are allowed to send topic content
+	// directly to the hub, which is disabled if it's empty.
+	PublishToken string `env:"PUBLISH_TOKEN"`
+
+	// Require authentication of every publish request by the publish token,
+	// or by the token or signature of publisher registered by admin.
+	PublishAuth bool `env:"PUBLISH_AUTH" envDefault:"false"`
+
+	// Maximum number of known topics which a single prefix publish
+	// request can refresh.
+	PublishPrefixLimit uint `env:"PUBLISH_PREFIX_LIMIT" envDefault:"100"`
+
+	// Sort query parameters of topic URLs, so topics which differ only by
+	// parameters order are the same topic.
+	SortQuery bool `env:"SORT_QUERY" envDefault:"false"`
+
+	// Host and path patterns of topics and callbacks which the hub accepts
+	// subscriptions for, such as "example.com" or "*.example.com/blog/*".
+	// Deny lists take precedence, empty allow list allows everything.
+	TopicAllow    []string `env:"TOPIC_ALLOW" envSeparator:","`
+	TopicDeny     []string `env:"TOPIC_DENY" envSeparator:","`
+	CallbackAllow []string `env:"CALLBACK_ALLOW" envSeparator:","`
+	CallbackDeny  []string `env:"CALLBACK_DENY" envSeparator:","`
+
+	// Path of the file with additional "<allow|deny> <topic|callback>
+	// <pattern>" policy rules, one per line. Policy is reloaded from it on
+	// SIGHUP.
+	PolicyFile string `env:"POLICY_FILE"`
+
+	// Number of the last content versions of each topic which are kept for
+	// re-delivery, zero disables it.
+	TopicVersions uint `env:"TOPIC_VERSIONS" envDefault:"10"`
+
+	// Reject topics which does not advertise BaseURL as their hub.
+	StrictDiscovery bool `env:"STRICT_DISCOVERY" envDefault:"false"`
+
+	// Number of consecutive 404/410 topic fetches after which topic will
+	// be retired, zero disables it.
+	RetireAfter uint `env:"RETIRE_AFTER" envDefault:"3"`
+
+	// Minimum and maximum intervals of adaptive topics polling, zero
+	// minimum disables it.
+	PollInterval    time.Duration `env:"POLL_INTERVAL" envDefault:"15m"`
+	PollIntervalMax time.Duration `env:"POLL_INTERVAL_MAX" envDefault:"24h"`
+
+	// Maximum number of concurrent topics polls.
+	PollWorkers uint `env:"POLL_WORKERS" envDefault:"4"`
+
+	// Maximum number of content distribution attempts before the job
+	// will be marked as dead.
+	DeliveryAttempts uint `env:"DELIVERY_ATTEMPTS" envDefault:"10"`
+
+	// Initial and maximum delays between content distribution attempts.
+	DeliveryBackoff    time.Duration `env:"DELIVERY_BACKOFF" envDefault:"30s"`
+	DeliveryBackoffMax time.Duration `env:"DELIVERY_BACKOFF_MAX" envDefault:"6h"`
+
+	// Retention period of deliveries history, zero keeps it forever.
+	DeliveryHistory time.Duration `env:"DELIVERY_HISTORY" envDefault:"168h"`
+
+	// Number of consecutive failed deliveries after which subscription is
+	// suspended and probed once per ProbeInterval, zero disables it.
+	// Suspended subscriptions are removed with denial after
+	// SuspendTimeout, if it's not zero.
+	SuspendAfter   uint          `env:"SUSPEND_AFTER" envDefault:"10"`
+	ProbeInterval  time.Duration `env:"PROBE_INTERVAL" envDefault:"1h"`
+	SuspendTimeout time.Duration `env:"SUSPEND_TIMEOUT" envDefault:"72h"`
+
+	// Maximum number of concurrent content distributions in total and per
+	// single callback host.
+	DeliveryWorkers        uint `env:"DELIVERY_WORKERS" envDefault:"16"`
+	DeliveryWorkersPerHost uint `env:"DELIVERY_WORKERS_PER_HOST" envDefault:"2"`
+
+	// Minimum, maximum and default leases of subscriptions. Requested
+	// leases out of range are clamped, zero maximum disables the upper
+	// limit.
+	LeaseMin     time.Duration `env:"LEASE_MIN" envDefault:"5m"`
+	LeaseMax     time.Duration `env:"LEASE_MAX" envDefault:"720h"`
+	LeaseDefault time.Duration `env:"LEASE_DEFAULT" envDefault:"240h"`
+
+	// Window before lease expiry in which hub re-verifies subscriber with a
+	// new challenge and extends it's lease on success, zero disables
+	// automatic renewal. It should be longer than VerifyTimeout and shorter
+	// than LeaseMin.
+	RenewBefore time.Duration `env:"RENEW_BEFORE" envDefault:"0"`
+
+	// Maximum number of verification of intent attempts, timeout and
+	// delay between them.
+	VerifyAttempts uint          `env:"VERIFY_ATTEMPTS" envDefault:"3"`
+	VerifyTimeout  time.Duration `env:"VERIFY_TIMEOUT" envDefault:"10s"`
+	VerifyBackoff  time.Duration `env:"VERIFY_BACKOFF" envDefault:"5s"`
+
+	// Maximum number of concurrent asynchronous verifications of intent
+	// and denial notifications.
+	VerifyWorkers uint `env:"VERIFY_WORKERS" envDefault:"64"`
 }
 
 func TestConfig(tb testing.TB) *Config {
@@ -21,7 +125,31 @@ func TestConfig(tb testing.TB) *Config {
 			Host:   "hub.example.com",
 			Path:   "/",
 		},
-		Bind: ":3000",
-		Name: "WebSub",
+		Bind:                   ":3000",
+		Name:                   "WebSub",
+		AdminToken:             "admin",
+		PublishToken:           "publisher",
+		PublishPrefixLimit:     100,
+		RetireAfter:            3,
+		TopicVersions:          10,
+		PollInterval:           15 * time.Minute,
+		PollIntervalMax:        24 * time.Hour,
+		PollWorkers:            2,
+		DeliveryAttempts:       10,
+		DeliveryBackoff:        30 * time.Second,
+		DeliveryBackoffMax:     6 * time.Hour,
+		DeliveryHistory:        7 * 24 * time.Hour,
+		SuspendAfter:           10,
+		ProbeInterval:          time.Hour,
+		SuspendTimeout:         72 * time.Hour,
+		DeliveryWorkers:        4,
+		DeliveryWorkersPerHost: 1,
+		LeaseMin:               5 * time.Minute,
+		LeaseMax:               30 * 24 * time.Hour,
+		LeaseDefault:           10 * 24 * time.Hour,
+		VerifyAttempts:         1,
+		VerifyTimeout:          time.Second,
+		VerifyBackoff:          time.Millisecond,
+		VerifyWorkers:          4,
 	}
 }
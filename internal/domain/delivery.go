@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Delivery is a record of a single content distribution attempt of topic
+// version to subscriber.
+type Delivery struct {
+	// Datetime of attempt
+	CreatedAt time.Time
+
+	// Topic updating datetime which was delivered
+	Version time.Time
+
+	// Identifier of topic content version which was delivered
+	VersionID uint64
+
+	Topic    *url.URL
+	Callback *url.URL
+
+	// Truncated subscriber response body, if any
+	Body string
+
+	// Attempt error, if any
+	Error string
+
+	// Duration of subscriber response
+	Latency time.Duration
+
+	// Number of attempt of this topic version delivery, starting from 1
+	Attempt uint
+
+	// Subscriber response status code, if any
+	Status int
+}
+
+func (d Delivery) SUID() SUID {
+	return SUID{
+		topic:    d.Topic,
+		callback: d.Callback,
+	}
+}
+
+// Failed reports whether subscriber did not accept delivered content.
+func (d Delivery) Failed() bool {
+	return d.Error != ""
+}
+
+func TestDelivery(tb testing.TB) *Delivery {
+	tb.Helper()
+
+	ts := time.Now().UTC().Round(time.Second)
+
+	return &Delivery{
+		CreatedAt: ts,
+		Version:   ts.Add(-1 * time.Hour),
+		VersionID: 1,
+		Topic:     &url.URL{Scheme: "https", Host: "example.com", Path: "/lipsum"},
+		Callback:  &url.URL{Scheme: "https", Host: "example.net", Path: "/callback"},
+		Body:      "OK",
+		Latency:   150 * time.Millisecond,
+		Attempt:   1,
+		Status:    200,
+	}
+}
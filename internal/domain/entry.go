@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// Entry is a record of a single entry of feed topic content.
+type Entry struct {
+	// Topic updating datetime when entry was added or changed
+	UpdatedAt time.Time
+
+	ID string
+
+	// Topic content version when entry was added or changed
+	VersionID uint64
+
+	// Checksum of the entry, see NewContentHash
+	Hash string
+}
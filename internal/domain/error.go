@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 
@@ -53,3 +54,34 @@ func (e Error) FormatError(printer xerrors.Printer) error {
 
 	return nil
 }
+
+// Reasons of denied subscriptions which are sent to subscribers as hub.reason.
+var (
+	ErrReasonSecret   = NewError("the subscriber secret is invalid")
+	ErrReasonCallback = NewError("the callback is not allowed by this hub")
+	ErrReasonTopic    = NewError("the topic is unreachable")
+	ErrReasonPolicy   = NewError("the subscription violates the hub policy")
+	ErrReasonRevoked  = NewError("the subscription was revoked by the hub")
+	ErrReasonRetired  = NewError("the topic was retired by the publisher")
+	ErrReasonFailed   = NewError("the callback failed to accept content for too long")
+)
+
+// Reason returns a known denial reason which is wrapped by err, or err itself
+// otherwise.
+func Reason(err error) error {
+	for _, reason := range []error{
+		ErrReasonSecret,
+		ErrReasonCallback,
+		ErrReasonTopic,
+		ErrReasonPolicy,
+		ErrReasonRevoked,
+		ErrReasonRetired,
+		ErrReasonFailed,
+	} {
+		if errors.Is(err, reason) {
+			return reason
+		}
+	}
+
+	return err
+}
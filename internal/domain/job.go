@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/common"
+)
+
+// Job is a queued content distribution of topic updates to a single
+// subscription which survives hub restarts and subscriber downtime.
+type Job struct {
+	// First creation datetime
+	CreatedAt time.Time
+
+	// Last updating datetime
+	UpdatedAt time.Time
+
+	// Datetime of the next delivery attempt
+	ScheduledAt time.Time
+
+	// Topic updating datetime which must be delivered
+	Version time.Time
+
+	// Identifier of topic content version which must be delivered
+	VersionID uint64
+
+	// Deliver the full content of VersionID even if subscriber is already
+	// synced with it
+	Replay bool
+
+	Topic    *url.URL
+	Callback *url.URL
+
+	// Last delivery attempt error, if any
+	Error string
+
+	// Number of failed delivery attempts
+	Attempts uint
+
+	// Last subscriber response status code, if any
+	Status int
+
+	State JobState
+}
+
+type JobState struct {
+	state string
+}
+
+var (
+	JobStateUnd     JobState = JobState{state: ""}        // "und"
+	JobStatePending JobState = JobState{state: "pending"} // "pending"
+	JobStateDead    JobState = JobState{state: "dead"}    // "dead"
+)
+
+var ErrJobStateSyntax = errors.New("bad job state syntax")
+
+var stringsJobStates = map[string]JobState{
+	JobStatePending.state: JobStatePending,
+	JobStateDead.state:    JobStateDead,
+}
+
+func NewJob(s Subscription, t Topic, ts time.Time) *Job {
+	return &Job{
+		CreatedAt:   ts,
+		UpdatedAt:   ts,
+		ScheduledAt: ts,
+		Version:     t.UpdatedAt,
+		VersionID:   t.VersionID,
+		Topic:       s.Topic,
+		Callback:    s.Callback,
+		State:       JobStatePending,
+	}
+}
+
+func (j Job) SUID() SUID {
+	return newSUID(j.Topic, j.Callback)
+}
+
+// NewReplayJob returns job which delivers the content of topic version v to
+// subscription once again.
+func NewReplayJob(s Subscription, v TopicVersion, ts time.Time) *Job {
+	return &Job{
+		CreatedAt:   ts,
+		UpdatedAt:   ts,
+		ScheduledAt: ts,
+		Version:     v.UpdatedAt,
+		VersionID:   v.ID,
+		Topic:       s.Topic,
+		Callback:    s.Callback,
+		State:       JobStatePending,
+		Replay:      true,
+	}
+}
+
+// Ready reports whether job must be delivered at ts.
+func (j Job) Ready(ts time.Time) bool {
+	return j.State == JobStatePending && !j.ScheduledAt.After(ts)
+}
+
+// Outdated reports whether topic contains updates which is not covered by job.
+func (j Job) Outdated(t Topic) bool {
+	return t.VersionID > j.VersionID
+}
+
+func TestJob(tb testing.TB) *Job {
+	tb.Helper()
+
+	ts := time.Now().UTC().Round(time.Second)
+
+	return &Job{
+		CreatedAt:   ts,
+		UpdatedAt:   ts,
+		ScheduledAt: ts,
+		Version:     ts.Add(-1 * time.Hour),
+		Topic:       &url.URL{Scheme: "https", Host: "example.com", Path: "/lipsum"},
+		Callback:    &url.URL{Scheme: "https", Host: "example.net", Path: "/callback"},
+		State:       JobStatePending,
+	}
+}
+
+func ParseJobState(state string) (JobState, error) {
+	if s, ok := stringsJobStates[state]; ok {
+		return s, nil
+	}
+
+	return JobStateUnd, fmt.Errorf("%w: %s", ErrJobStateSyntax, state)
+}
+
+func (js JobState) String() string {
+	if js.state != "" {
+		return js.state
+	}
+
+	return common.Und
+}
+
+func (js JobState) GoString() string {
+	return "domain.JobState(" + js.String() + ")"
+}
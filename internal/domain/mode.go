@@ -16,6 +16,8 @@ var (
 	ModeUnd         Mode = Mode{mode: ""}            // "und"
 	ModeDenied      Mode = Mode{mode: "denied"}      // "denied"
 	ModePublish     Mode = Mode{mode: "publish"}     // "publish"
+	ModeReplay      Mode = Mode{mode: "replay"}      // "replay"
+	ModeRetire      Mode = Mode{mode: "retire"}      // "retire"
 	ModeSubscribe   Mode = Mode{mode: "subscribe"}   // "subscribe"
 	ModeUnsubscribe Mode = Mode{mode: "unsubscribe"} // "unsubscribe"
 )
@@ -25,6 +27,8 @@ var ErrModeSyntax = errors.New("bad mode syntax")
 var stringsModes = map[string]Mode{
 	ModeDenied.mode:      ModeDenied,
 	ModePublish.mode:     ModePublish,
+	ModeReplay.mode:      ModeReplay,
+	ModeRetire.mode:      ModeRetire,
 	ModeSubscribe.mode:   ModeSubscribe,
 	ModeUnsubscribe.mode: ModeUnsubscribe,
 }
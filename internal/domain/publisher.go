@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+// Publisher describes a trusted publisher which is allowed to publish topics
+// under it's URL prefixes. Publisher authenticates by bearer token or by HMAC
+// signature of the request computed with Secret, see SignedMessage.
+type Publisher struct {
+	CreatedAt time.Time
+
+	// Prefixes of topic URLs which publisher is allowed to publish, any
+	// topic if it's empty
+	Prefixes []*url.URL
+
+	// Public identifier of publisher which is sent with signed requests
+	ID string
+
+	// Checksum of the bearer token, see NewContentHash
+	Token string
+
+	// Key of HMAC signatures of publish requests
+	Secret Secret
+}
+
+func TestPublisher(tb testing.TB) *Publisher {
+	tb.Helper()
+
+	return &Publisher{
+		CreatedAt: time.Now().UTC().Add(-1 * time.Hour).Round(time.Second),
+		Prefixes:  []*url.URL{{Scheme: "https", Host: "example.com", Path: "/"}},
+		ID:        "lipsum",
+		Token:     NewContentHash([]byte("lipsum")),
+		Secret:    *TestSecret(tb),
+	}
+}
+
+// SignedMessage returns a message of publish request which publisher signs:
+// request method, URL without scheme, unix timestamp and raw body, separated by
+// newlines. The scheme is not signed, because TLS may be terminated by a proxy
+// in front of the hub.
+func SignedMessage(method string, u *url.URL, timestamp string, body []byte) []byte {
+	out := make([]byte, 0, len(method)+len(u.Host)+len(u.RequestURI())+len(timestamp)+len(body)+3)
+	out = append(out, method+"\n"+u.Host+u.RequestURI()+"\n"+timestamp+"\n"...)
+
+	return append(out, body...)
+}
+
+// Allows reports whether publisher is allowed to publish topic u, or topics
+// under the u prefix. Prefixes are matched on path segments boundary, see
+// urlutil.HasPrefix.
+func (p Publisher) Allows(u *url.URL) bool {
+	if len(p.Prefixes) == 0 {
+		return true
+	}
+
+	for i := range p.Prefixes {
+		if urlutil.HasPrefix(u, p.Prefixes[i]) {
+			return true
+		}
+	}
+
+	return false
+}
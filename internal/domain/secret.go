@@ -55,7 +55,7 @@ func (s Secret) String() string {
 func TestSecret(tb testing.TB) *Secret {
 	tb.Helper()
 
-	src := make([]byte, rand.Intn(SecretLength/2))
+	src := make([]byte, 1+rand.Intn(SecretLength/2))
 	if _, err := cryptorand.Read(src); err != nil {
 		tb.Fatal(err)
 	}
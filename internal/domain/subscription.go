@@ -24,24 +24,52 @@ type Subscription struct {
 	// Datetime synced with topic updating time
 	SyncedAt time.Time
 
+	// Identifier of the last topic content version received by subscriber
+	SyncedVersionID uint64
+
+	// Datetime of the last automatic re-verification of subscriber before
+	// lease expiry
+	ReverifiedAt time.Time
+
+	// Datetime when lease was extended by the last successful automatic
+	// re-verification
+	RenewedAt time.Time
+
+	// Datetime until which deliveries are paused because subscriber asked
+	// to retry after it
+	PausedUntil time.Time
+
+	// Datetime when subscription was suspended by opened circuit
+	SuspendedAt time.Time
+
+	// Datetime of the next probing delivery to suspended subscription
+	ProbeAt time.Time
+
+	// State of callback circuit breaker
+	Circuit CircuitState
+
+	// Number of consecutive failed deliveries
+	Failures uint
+
 	Callback *url.URL
 	Topic    *url.URL
 
 	Secret Secret
+
+	// Receive the full topic content instead of new and updated feed
+	// entries only
+	Full bool
 }
 
 func (s Subscription) AddQuery(q url.Values) {
 	s.Secret.AddQuery(q)
 	q.Add(common.HubTopic, s.Topic.String())
 	q.Add(common.HubCallback, s.Callback.String())
-	q.Add(common.HubLeaseSeconds, strconv.FormatFloat(s.LeaseSeconds(), 'g', 0, 64))
+	q.Add(common.HubLeaseSeconds, strconv.FormatFloat(s.LeaseSeconds(), 'f', 0, 64))
 }
 
 func (s Subscription) SUID() SUID {
-	return SUID{
-		topic:    s.Topic,
-		callback: s.Callback,
-	}
+	return newSUID(s.Topic, s.Callback)
 }
 
 func (s Subscription) LeaseSeconds() float64 {
@@ -49,7 +77,18 @@ func (s Subscription) LeaseSeconds() float64 {
 }
 
 func (s Subscription) Synced(t Topic) bool {
-	return s.SyncedAt.Equal(t.UpdatedAt) || s.SyncedAt.After(t.UpdatedAt)
+	return s.SyncedVersionID >= t.VersionID
+}
+
+// Suspended reports whether content distribution to subscriber is stopped by
+// opened circuit, except of rare probes.
+func (s Subscription) Suspended() bool {
+	return s.Circuit == CircuitStateOpen || s.Circuit == CircuitStateHalfOpen
+}
+
+// Paused reports whether deliveries to subscriber are paused at ts.
+func (s Subscription) Paused(ts time.Time) bool {
+	return s.PausedUntil.After(ts)
 }
 
 func (s Subscription) Expired(ts time.Time) bool {
@@ -74,5 +113,6 @@ func TestSubscription(tb testing.TB, callbackUrl string) *Subscription {
 		Callback:  callback,
 		Topic:     &url.URL{Scheme: "https", Host: "example.com", Path: "/lipsum"},
 		Secret:    *secret,
+		Circuit:   CircuitStateClosed,
 	}
 }
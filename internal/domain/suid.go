@@ -1,18 +1,27 @@
 package domain
 
-import "net/url"
+import (
+	"net/url"
+
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
 
 // SUID describes a subscription's unique key is the tuple ([Topic] URL,
-// Subscriber [Callback] URL).
+// Subscriber [Callback] URL). Both URLs are stored in the canonical form, so
+// equivalent URLs make the same SUID.
 type SUID struct {
 	topic    *url.URL
 	callback *url.URL
 }
 
 func NewSSID(topic Topic, callback *url.URL) SUID {
+	return newSUID(topic.Self, callback)
+}
+
+func newSUID(topic, callback *url.URL) SUID {
 	return SUID{
-		topic:    topic.Self,
-		callback: callback,
+		topic:    urlutil.Canonical(topic),
+		callback: urlutil.Canonical(callback),
 	}
 }
 
@@ -29,7 +38,7 @@ func (suid SUID) Callback() *url.URL {
 }
 
 func (suid SUID) Equal(target SUID) bool {
-	return suid.topic == target.topic && suid.callback == target.callback
+	return suid.topic.String() == target.topic.String() && suid.callback.String() == target.callback.String()
 }
 
 func (suid SUID) GoString() string {
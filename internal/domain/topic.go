@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/url"
 	"testing"
 	"time"
@@ -14,6 +16,33 @@ type Topic struct {
 	Self        *url.URL
 	ContentType string
 	Content     []byte
+
+	// Canonical URL of topic discovered in it's rel=self link, if any
+	Canonical *url.URL
+
+	// Validators of the last fetched content, which are sent back in
+	// conditional requests
+	ETag         string
+	LastModified string
+
+	// Checksum of Content, see NewContentHash
+	Hash string
+
+	// Identifier of the current content version, which is incremented on
+	// every content change
+	VersionID uint64
+
+	// Records of entries if Content is a feed document
+	Entries []Entry
+
+	// Number of consecutive fetches which reports that topic was removed
+	Failures uint
+
+	// Datetime of the next polling of topic by hub
+	PollAt time.Time
+
+	// Polling interval override, adaptive polling is used if it's zero
+	PollInterval time.Duration
 }
 
 func TestTopic(tb testing.TB) *Topic {
@@ -27,9 +56,43 @@ func TestTopic(tb testing.TB) *Topic {
 		Self:        &url.URL{Scheme: "https", Host: "example.com", Path: "/"},
 		ContentType: "text/html",
 		Content:     []byte("hello, world"),
+		Hash:        NewContentHash([]byte("hello, world")),
+		VersionID:   1,
 	}
 }
 
+// NewContentHash returns a hex-encoded SHA-256 checksum of topic content.
+func NewContentHash(content []byte) string {
+	hash := sha256.Sum256(content)
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Version returns a snapshot of the current topic content.
+func (t Topic) Version() TopicVersion {
+	return TopicVersion{
+		UpdatedAt:   t.UpdatedAt,
+		Topic:       t.Self,
+		ContentType: t.ContentType,
+		Content:     t.Content,
+		Hash:        t.Hash,
+		ID:          t.VersionID,
+	}
+}
+
+// Rewind returns topic with the content of version v. It contains no feed
+// entries, so the content is distributed in full.
+func (t Topic) Rewind(v TopicVersion) Topic {
+	t.UpdatedAt = v.UpdatedAt
+	t.ContentType = v.ContentType
+	t.Content = v.Content
+	t.Hash = v.Hash
+	t.VersionID = v.ID
+	t.Entries = nil
+
+	return t
+}
+
 func (t Topic) AddQuery(q url.Values) {
 	q.Add(common.HubTopic, t.Self.String())
 }
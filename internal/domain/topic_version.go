@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"net/url"
+	"time"
+)
+
+// TopicVersion is a retained snapshot of topic content, so subscribers which
+// missed it can receive it later.
+type TopicVersion struct {
+	// Topic updating datetime of this content
+	UpdatedAt time.Time
+
+	Topic       *url.URL
+	ContentType string
+	Content     []byte
+
+	// Checksum of Content, see NewContentHash
+	Hash string
+
+	// Identifier of version, see Topic.VersionID
+	ID uint64
+}
@@ -0,0 +1,284 @@
+// Package feed parses Atom, RSS 2.0 and JSON Feed topic contents into entries
+// and rebuilds them with a subset of entries, so subscribers can receive only
+// new and updated ones.
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+)
+
+type (
+	// Feed is a parsed topic content.
+	Feed struct {
+		content []byte
+		format  format
+		entries []entry
+		// NOTE(toby3d): members of JSON Feed document except items.
+		object map[string]json.RawMessage
+	}
+
+	entry struct {
+		id   string
+		hash string
+		// NOTE(toby3d): bounds of the XML element in the content.
+		start, end int64
+		// NOTE(toby3d): raw JSON Feed item.
+		raw json.RawMessage
+	}
+
+	format uint8
+)
+
+const (
+	formatAtom format = iota + 1
+	formatRSS
+	formatJSON
+)
+
+const (
+	namespaceAtom string = "http://www.w3.org/2005/Atom"
+	prefixJSON    string = "https://jsonfeed.org/version/"
+)
+
+// ErrFormat reports that topic content is not a supported feed document.
+var ErrFormat = errors.New("content is not an Atom, RSS or JSON feed")
+
+// Parse parses content of contentType as a feed document.
+func Parse(contentType string, content []byte) (*Feed, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		return parseJSON(content)
+	case strings.HasSuffix(mediaType, "xml"):
+		return parseXML(content)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrFormat, mediaType)
+	}
+}
+
+// Record returns records of feed entries of topic content, if it's a feed. The
+// updating time and version of entries which are not changed since known
+// records are kept, other entries are updated at the topic updating time and
+// version.
+func Record(t domain.Topic, known []domain.Entry) []domain.Entry {
+	f, err := Parse(t.ContentType, t.Content)
+	if err != nil {
+		return nil
+	}
+
+	hashes := make(map[string]domain.Entry, len(known))
+	for i := range known {
+		hashes[known[i].ID] = known[i]
+	}
+
+	out := f.Entries()
+	for i := range out {
+		out[i].UpdatedAt = t.UpdatedAt
+		out[i].VersionID = t.VersionID
+
+		if e, ok := hashes[out[i].ID]; ok && e.Hash == out[i].Hash {
+			out[i].UpdatedAt = e.UpdatedAt
+			out[i].VersionID = e.VersionID
+		}
+	}
+
+	return out
+}
+
+// Entries returns records of parsed feed entries in the document order. The
+// UpdatedAt of each record is not set.
+func (f Feed) Entries() []domain.Entry {
+	out := make([]domain.Entry, len(f.entries))
+
+	for i := range f.entries {
+		out[i] = domain.Entry{ID: f.entries[i].id, Hash: f.entries[i].hash}
+	}
+
+	return out
+}
+
+// Filter returns a feed document which contains only entries reported by keep.
+func (f Feed) Filter(keep func(id string) bool) ([]byte, error) {
+	if f.format == formatJSON {
+		items := make([]json.RawMessage, 0, len(f.entries))
+
+		for i := range f.entries {
+			if keep(f.entries[i].id) {
+				items = append(items, f.entries[i].raw)
+			}
+		}
+
+		object := make(map[string]any, len(f.object)+1)
+		for k, v := range f.object {
+			object[k] = v
+		}
+
+		object["items"] = items
+
+		out, err := json.Marshal(object)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode filtered JSON feed: %w", err)
+		}
+
+		return out, nil
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(f.content)))
+	offset := int64(0)
+
+	for i := range f.entries {
+		if keep(f.entries[i].id) {
+			continue
+		}
+
+		out.Write(f.content[offset:f.entries[i].start])
+		offset = f.entries[i].end
+	}
+
+	out.Write(f.content[offset:])
+
+	return out.Bytes(), nil
+}
+
+func parseJSON(content []byte) (*Feed, error) {
+	out := &Feed{
+		content: content,
+		format:  formatJSON,
+		object:  make(map[string]json.RawMessage),
+	}
+
+	if err := json.Unmarshal(content, &out.object); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+
+	var version string
+	if err := json.Unmarshal(out.object["version"], &version); err != nil ||
+		!strings.HasPrefix(version, prefixJSON) {
+		return nil, fmt.Errorf("%w: unknown JSON feed version", ErrFormat)
+	}
+
+	items := make([]json.RawMessage, 0)
+	if raw, ok := out.object["items"]; ok {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+		}
+	}
+
+	delete(out.object, "items")
+
+	for i := range items {
+		var item struct {
+			ID json.RawMessage `json:"id"`
+		}
+
+		if err := json.Unmarshal(items[i], &item); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+		}
+
+		// NOTE(toby3d): id is a string, but some publishers send numbers.
+		id := strings.Trim(string(item.ID), `"`)
+		out.entries = append(out.entries, newEntry(id, items[i]))
+		out.entries[i].raw = items[i]
+	}
+
+	return out, nil
+}
+
+func parseXML(content []byte) (*Feed, error) {
+	out := &Feed{content: content}
+
+	d := xml.NewDecoder(bytes.NewReader(content))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	depth := 0
+
+	for {
+		start := d.InputOffset()
+
+		token, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			depth++
+
+			if depth == 1 {
+				switch {
+				case t.Name.Local == "feed" && t.Name.Space == namespaceAtom:
+					out.format = formatAtom
+				case t.Name.Local == "rss":
+					out.format = formatRSS
+				default:
+					return nil, fmt.Errorf("%w: unknown root element %s", ErrFormat, t.Name.Local)
+				}
+
+				continue
+			}
+
+			// NOTE(toby3d): entries are children of feed in Atom and
+			// of rss/channel in RSS documents.
+			isEntry := (out.format == formatAtom && depth == 2 && t.Name.Local == "entry") ||
+				(out.format == formatRSS && depth == 3 && t.Name.Local == "item")
+			if !isEntry {
+				continue
+			}
+
+			var element struct {
+				ID   string `xml:"id"`
+				GUID string `xml:"guid"`
+				Link string `xml:"link"`
+			}
+
+			if err = d.DecodeElement(&element, &t); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrFormat, err)
+			}
+
+			depth--
+
+			id := strings.TrimSpace(element.ID + element.GUID)
+			if id == "" {
+				id = strings.TrimSpace(element.Link)
+			}
+
+			e := newEntry(id, content[start:d.InputOffset()])
+			e.start, e.end = start, d.InputOffset()
+			out.entries = append(out.entries, e)
+		}
+	}
+
+	if out.format == 0 {
+		return nil, fmt.Errorf("%w: empty document", ErrFormat)
+	}
+
+	return out, nil
+}
+
+// newEntry creates entry record of raw content. Entries without identifier are
+// identified by their content.
+func newEntry(id string, raw []byte) entry {
+	out := entry{id: id, hash: domain.NewContentHash(raw)}
+	if out.id == "" {
+		out.id = out.hash
+	}
+
+	return out
+}
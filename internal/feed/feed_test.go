@@ -0,0 +1,142 @@
+package feed_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/feed"
+)
+
+const (
+	testAtom string = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Lipsum</title>
+	<id>urn:lipsum</id>
+	<entry><id>urn:lipsum:1</id><title>First</title></entry>
+	<entry><id>urn:lipsum:2</id><title>Second</title></entry>
+</feed>`
+	testRSS string = `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0">
+	<channel>
+		<title>Lipsum</title>
+		<item><guid>urn:lipsum:1</guid><title>First</title></item>
+		<item><link>https://example.com/2</link><title>Second</title></item>
+	</channel>
+</rss>`
+	testJSON string = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Lipsum",
+	"items": [
+		{"id": "urn:lipsum:1", "title": "First"},
+		{"id": "urn:lipsum:2", "title": "Second"}
+	]
+}`
+)
+
+func TestFeed_Filter(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		contentType string
+		content     string
+		keep        string
+	}{
+		"atom": {contentType: "application/atom+xml", content: testAtom, keep: "urn:lipsum:2"},
+		"rss":  {contentType: "application/rss+xml; charset=utf-8", content: testRSS, keep: "https://example.com/2"},
+		"json": {contentType: "application/feed+json", content: testJSON, keep: "urn:lipsum:2"},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := feed.Parse(tc.contentType, []byte(tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if entries := f.Entries(); len(entries) != 2 {
+				t.Fatalf("want %d entries, got %d", 2, len(entries))
+			}
+
+			content, err := f.Filter(func(id string) bool { return id == tc.keep })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// NOTE(toby3d): filtered document must be a valid feed too.
+			filtered, err := feed.Parse(tc.contentType, content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries := filtered.Entries()
+			if len(entries) != 1 || entries[0].ID != tc.keep {
+				t.Errorf("want only '%s' entry, got %+v", tc.keep, entries)
+			}
+		})
+	}
+}
+
+func TestParse_Format(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		contentType string
+		content     string
+	}{
+		"html": {contentType: "text/html", content: "<html></html>"},
+		"xml":  {contentType: "application/xml", content: "<html></html>"},
+		"json": {contentType: "application/json", content: `{"title": "Lipsum"}`},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := feed.Parse(tc.contentType, []byte(tc.content)); !errors.Is(err, feed.ErrFormat) {
+				t.Errorf("want %v error, got %v", feed.ErrFormat, err)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Now().UTC().Round(time.Second)
+	topic := domain.Topic{
+		UpdatedAt:   ts,
+		ContentType: "application/atom+xml",
+		Content:     []byte(testAtom),
+		VersionID:   2,
+	}
+
+	known := feed.Record(domain.Topic{
+		VersionID:   1,
+		UpdatedAt:   ts.Add(-1 * time.Hour),
+		ContentType: topic.ContentType,
+		Content:     topic.Content,
+	}, nil)
+	known[1].Hash = "changed"
+
+	actual := feed.Record(topic, known)
+	if len(actual) != 2 {
+		t.Fatalf("want %d entries, got %d", 2, len(actual))
+	}
+
+	if !actual[0].UpdatedAt.Equal(known[0].UpdatedAt) {
+		t.Errorf("want unchanged entry updated at %s, got %s", known[0].UpdatedAt, actual[0].UpdatedAt)
+	}
+
+	if !actual[1].UpdatedAt.Equal(ts) {
+		t.Errorf("want changed entry updated at %s, got %s", ts, actual[1].UpdatedAt)
+	}
+
+	if actual[0].VersionID != 1 || actual[1].VersionID != 2 {
+		t.Errorf("want entries of versions %d and %d, got %d and %d", 1, 2, actual[0].VersionID,
+			actual[1].VersionID)
+	}
+}
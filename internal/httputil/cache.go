@@ -0,0 +1,69 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/common"
+)
+
+// RetryAfter returns the delay requested by Retry-After header value, which may
+// be a delta-seconds or an HTTP-date relative to now.
+func RetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+
+	return 0, true
+}
+
+// Freshness returns the lifetime of response described by it's Cache-Control
+// max-age directive or Expires header relative to now.
+func Freshness(header http.Header, now time.Time) (time.Duration, bool) {
+	for _, directive := range strings.Split(header.Get(common.HeaderCacheControl), ",") {
+		value, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(directive)), "max-age=")
+		if !ok {
+			continue
+		}
+
+		seconds, err := strconv.ParseUint(strings.Trim(value, `"`), 10, 32)
+		if err != nil {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if strings.TrimSpace(header.Get(common.HeaderExpires)) != "" {
+		expires, err := http.ParseTime(header.Get(common.HeaderExpires))
+		if err != nil {
+			// NOTE(toby3d): invalid Expires value, such as "0", means
+			// an already expired response.
+			return 0, true
+		}
+
+		if lifetime := expires.Sub(now); lifetime > 0 {
+			return lifetime, true
+		}
+
+		return 0, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,78 @@
+package httputil_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/httputil"
+)
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		input  string
+		expect time.Duration
+		ok     bool
+	}{
+		"empty":   {input: "", expect: 0, ok: false},
+		"seconds": {input: "120", expect: 2 * time.Minute, ok: true},
+		"date":    {input: now.Add(time.Hour).Format(http.TimeFormat), expect: time.Hour, ok: true},
+		"past":    {input: now.Add(-1 * time.Hour).Format(http.TimeFormat), expect: 0, ok: true},
+		"invalid": {input: "soon", expect: 0, ok: false},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, ok := httputil.RetryAfter(tc.input, now)
+			if actual != tc.expect || ok != tc.ok {
+				t.Errorf("RetryAfter(%q) = %s, %t, want %s, %t", tc.input, actual, ok, tc.expect, tc.ok)
+			}
+		})
+	}
+}
+
+func TestFreshness(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		header http.Header
+		expect time.Duration
+		ok     bool
+	}{
+		"empty":   {header: http.Header{}, expect: 0, ok: false},
+		"max-age": {header: http.Header{"Cache-Control": {"public, max-age=3600"}}, expect: time.Hour, ok: true},
+		"expires": {
+			header: http.Header{"Expires": {now.Add(time.Minute).Format(http.TimeFormat)}},
+			expect: time.Minute,
+			ok:     true,
+		},
+		"priority": {
+			header: http.Header{
+				"Cache-Control": {"max-age=60"},
+				"Expires":       {now.Add(time.Hour).Format(http.TimeFormat)},
+			},
+			expect: time.Minute,
+			ok:     true,
+		},
+		"expired": {header: http.Header{"Expires": {"0"}}, expect: 0, ok: true},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, ok := httputil.Freshness(tc.header, now)
+			if actual != tc.expect || ok != tc.ok {
+				t.Errorf("Freshness(%v) = %s, %t, want %s, %t", tc.header, actual, ok, tc.expect, tc.ok)
+			}
+		})
+	}
+}
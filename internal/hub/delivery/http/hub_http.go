@@ -1,12 +1,19 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/text/language"
@@ -14,18 +21,33 @@ import (
 	"source.toby3d.me/toby3d/hub/internal/common"
 	"source.toby3d.me/toby3d/hub/internal/domain"
 	"source.toby3d.me/toby3d/hub/internal/hub"
+	"source.toby3d.me/toby3d/hub/internal/policy"
+	"source.toby3d.me/toby3d/hub/internal/publisher"
 	"source.toby3d.me/toby3d/hub/internal/subscription"
 	"source.toby3d.me/toby3d/hub/internal/topic"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
 	"source.toby3d.me/toby3d/hub/web/template"
 )
 
 type (
 	Request struct {
-		Callback     *url.URL
-		Topic        *url.URL
+		Callback *url.URL
+		Topic    *url.URL
+		// Topics contains every requested topic of publish request,
+		// including Topic.
+		Topics []*url.URL
+		// Prefixes contains requested URL prefixes of publish request,
+		// such as "https://example.com/blog/*".
+		Prefixes     []*url.URL
 		Secret       domain.Secret
 		Mode         domain.Mode
 		LeaseSeconds float64
+		// Full requests the full topic content instead of new and
+		// updated feed entries only.
+		Full bool
+		// Version is a topic content version of replay request, zero
+		// means the current one.
+		Version uint64
 	}
 
 	Response struct {
@@ -40,32 +62,140 @@ type (
 		Topics        topic.UseCase
 		Matcher       language.Matcher
 		Name          string
+
+		// Maximum number of verification of intent attempts, timeout
+		// and delay between them.
+		VerifyAttempts uint
+		VerifyTimeout  time.Duration
+		VerifyBackoff  time.Duration
+		// Maximum number of concurrent asynchronous verifications of
+		// intent and denial notifications, zero means one.
+		VerifyWorkers uint
+
+		// Minimum, maximum and default leases of subscriptions, zero
+		// maximum disables the upper limit.
+		LeaseMin     time.Duration
+		LeaseMax     time.Duration
+		LeaseDefault time.Duration
+
+		// Bearer token of publishers which are allowed to send topic
+		// content directly to the hub and publish topics by prefix.
+		PublishToken string
+		// Maximum number of topics refreshed by a single prefix.
+		PublishPrefixLimit uint
+
+		// Sort query parameters of requested topic URLs.
+		SortQuery bool
+
+		// Policy of accepted subscriptions, everything is allowed if
+		// it's nil.
+		Policy *policy.Engine
+
+		// Trusted publishers authenticated by their own tokens or
+		// signatures, if not nil.
+		Publishers publisher.UseCase
+		// Require authentication of publishers for every publish
+		// request.
+		PublishAuth bool
 	}
 
 	Handler struct {
-		hub           hub.UseCase
-		subscriptions subscription.UseCase
-		topics        topic.UseCase
-		matcher       language.Matcher
-		name          string
+		hub            hub.UseCase
+		subscriptions  subscription.UseCase
+		topics         topic.UseCase
+		matcher        language.Matcher
+		name           string
+		verifyAttempts uint
+		verifyTimeout  time.Duration
+		verifyBackoff  time.Duration
+		leaseMin       time.Duration
+		leaseMax       time.Duration
+		leaseDefault   time.Duration
+		publishToken   string
+		prefixLimit    uint
+		sortQuery      bool
+		policy         *policy.Engine
+		publishers     publisher.UseCase
+		publishAuth    bool
+		// Context of asynchronous verifications, which is canceled by
+		// Shutdown.
+		ctx    context.Context
+		cancel context.CancelFunc
+		// Semaphore of concurrent asynchronous verifications
+		verifications chan struct{}
+		// In-flight asynchronous verifications
+		wg *sync.WaitGroup
 	}
+
+	rawBodyKey struct{}
 )
 
 var DefaultRequestLeaseSeconds = time.Duration(10 * 24 * time.Hour).Seconds() // 10 days
 
+// Values of hub.content subscription parameter: subscriber receives only new and
+// updated entries of feed topics by default, or the full topic content.
+const (
+	ContentDiff string = "diff"
+	ContentFull string = "full"
+)
+
+// MaxContentLength is a maximum size of topic content which publisher can send
+// directly to the hub.
+const MaxContentLength int64 = 10 << 20 // 10 MiB
+
+// MaxRequestTopics is a maximum number of topics in a single publish request.
+const MaxRequestTopics int = 100
+
+// SignatureWindow is a maximum difference between the signed timestamp of
+// publish request and the hub time.
+const SignatureWindow time.Duration = 5 * time.Minute
+
+// publishWorkers is a maximum number of topics fetched concurrently for a
+// single publish request.
+const publishWorkers int = 8
+
 var (
 	ErrHubMode = errors.New(common.HubMode + " MUST be " + domain.ModeSubscribe.String() + " or " +
 		domain.ModeUnsubscribe.String())
-	ErrHubSecret = errors.New(common.HubSecret + " SHOULD be specified when the request was made over HTTPS")
+	ErrHubSecret      = errors.New(common.HubSecret + " SHOULD be specified when the request was made over HTTPS")
+	ErrPublish        = errors.New("publishing of topics is disabled on this hub")
+	ErrPublishContent = errors.New("publishing of topic content is disabled on this hub")
+	ErrPublishPrefix  = errors.New("publishing of topics by prefix is disabled on this hub")
+	ErrRetire         = errors.New("retiring of topics is disabled on this hub")
+	ErrReplay         = errors.New("replaying of topics is disabled on this hub")
+	ErrVerifyBusy     = errors.New("too many pending verifications of intent, retry later")
 )
 
 func NewHandler(params NewHandlerParams) *Handler {
+	verifyWorkers := params.VerifyWorkers
+	if verifyWorkers == 0 {
+		verifyWorkers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Handler{
-		hub:           params.Hub,
-		matcher:       params.Matcher,
-		name:          params.Name,
-		subscriptions: params.Subscriptions,
-		topics:        params.Topics,
+		hub:            params.Hub,
+		matcher:        params.Matcher,
+		name:           params.Name,
+		subscriptions:  params.Subscriptions,
+		topics:         params.Topics,
+		verifyAttempts: params.VerifyAttempts,
+		verifyTimeout:  params.VerifyTimeout,
+		verifyBackoff:  params.VerifyBackoff,
+		leaseMin:       params.LeaseMin,
+		leaseMax:       params.LeaseMax,
+		leaseDefault:   params.LeaseDefault,
+		publishToken:   params.PublishToken,
+		prefixLimit:    params.PublishPrefixLimit,
+		sortQuery:      params.SortQuery,
+		policy:         params.Policy,
+		publishers:     params.Publishers,
+		publishAuth:    params.PublishAuth,
+		ctx:            ctx,
+		cancel:         cancel,
+		verifications:  make(chan struct{}, verifyWorkers),
+		wg:             new(sync.WaitGroup),
 	}
 }
 
@@ -76,45 +206,85 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	default:
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 	case http.MethodPost:
+		// NOTE(toby3d): signature of publish request is computed over
+		// the raw body, which is consumed by the form parsing.
+		if r.Header.Get(common.HeaderXHubSignature) != "" {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxContentLength))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("cannot read request body: %s", err),
+					http.StatusRequestEntityTooLarge)
+
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r = r.WithContext(context.WithValue(r.Context(), rawBodyKey{}, body))
+		}
+
+		if isContent(r) {
+			h.handleContent(w, r)
+
+			return
+		}
+
 		req := NewRequest()
+		if h.leaseDefault > 0 {
+			req.LeaseSeconds = h.leaseDefault.Seconds()
+		}
 
 		var err error
-		if err = req.bind(r); err != nil {
+		if err = req.bind(r, h.sortQuery); err != nil {
+			// NOTE(toby3d): subscription request is not accepted by hub,
+			// notify subscriber about it if it's possible.
+			if h.notifiable(*req, err) {
+				h.deny(req.Callback, NewResponse(domain.Topic{Self: req.Topic}, err))
+			}
+
 			http.Error(w, err.Error(), http.StatusBadRequest)
 
 			return
 		}
 
-		// TODO(toby3d): send denied ping to callback if it's not accepted by hub
+		if req.Mode == domain.ModeSubscribe && h.policy != nil {
+			if err = h.policy.Check(req.Topic, req.Callback); err != nil {
+				if h.notifiable(*req, err) {
+					h.deny(req.Callback, NewResponse(domain.Topic{Self: req.Topic}, err))
+				}
+
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+		}
+
+		// NOTE(toby3d): hub decides the lease of subscription, which is
+		// sent to the subscriber in the verification request.
+		req.LeaseSeconds = h.lease(req.LeaseSeconds)
 
 		s := new(domain.Subscription)
 		req.populate(s, now)
 
 		switch req.Mode {
 		case domain.ModeSubscribe, domain.ModeUnsubscribe:
-			if _, err = h.hub.Verify(r.Context(), *s, req.Mode); err != nil {
-				r.Clone(context.WithValue(r.Context(), "error", err))
-
-				w.WriteHeader(http.StatusAccepted)
+			// NOTE(toby3d): verification of intent is performed
+			// asynchronously after the request is acknowledged.
+			if !h.spawn(func(ctx context.Context) { h.intent(ctx, *s, req.Mode) }) {
+				w.Header().Set(common.HeaderRetryAfter, strconv.Itoa(int(h.verifyTimeout.Seconds())+1))
+				http.Error(w, ErrVerifyBusy.Error(), http.StatusServiceUnavailable)
 
 				return
 			}
 
-			switch req.Mode {
-			case domain.ModeSubscribe:
-				_, err = h.subscriptions.Subscribe(r.Context(), *s)
-			case domain.ModeUnsubscribe:
-				_, err = h.subscriptions.Unsubscribe(r.Context(), *s)
-			}
-		case domain.ModePublish:
-			_, err = h.topics.Publish(r.Context(), req.Topic)
-		}
+			w.WriteHeader(http.StatusAccepted)
 
-		if err != nil {
-			r.Clone(context.WithValue(r.Context(), "error", err))
+			return
+		case domain.ModePublish:
+			h.handlePublish(w, r, *req)
+		case domain.ModeRetire:
+			h.handleRetire(w, r, *req)
+		case domain.ModeReplay:
+			h.handleReplay(w, r, *req)
 		}
-
-		w.WriteHeader(http.StatusAccepted)
 	case "", http.MethodGet:
 		tags, _, _ := language.ParseAcceptLanguage(r.Header.Get(common.HeaderAcceptLanguage))
 		tag, _, _ := h.matcher.Match(tags...)
@@ -124,6 +294,424 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handlePublish concurrently fetches every requested topic and reports result
+// of each one in the response body, line by line. Requested prefixes are
+// expanded into known topics only for authenticated publishers.
+func (h *Handler) handlePublish(w http.ResponseWriter, r *http.Request, req Request) {
+	topics := req.Topics
+
+	if h.publishAuth || len(req.Prefixes) > 0 {
+		disabled := ErrPublish
+		if len(req.Prefixes) > 0 {
+			disabled = ErrPublishPrefix
+		}
+
+		scope := make([]*url.URL, 0, len(req.Topics)+len(req.Prefixes))
+		scope = append(append(scope, req.Topics...), req.Prefixes...)
+
+		if !h.authorize(w, r, disabled, scope...) {
+			return
+		}
+	}
+
+	for _, prefix := range req.Prefixes {
+		known, err := h.topics.Search(r.Context(), prefix)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		if uint(len(known)) > h.prefixLimit {
+			http.Error(w, fmt.Sprintf("prefix %s* matches %d topics, but only %d are allowed", prefix,
+				len(known), h.prefixLimit), http.StatusForbidden)
+
+			return
+		}
+
+		for i := range known {
+			if !containsURL(topics, known[i].Self) {
+				topics = append(topics, known[i].Self)
+			}
+		}
+	}
+
+	errs := make([]error, len(topics))
+	wg := new(sync.WaitGroup)
+	semaphore := make(chan struct{}, publishWorkers)
+
+	for i := range topics {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			_, errs[i] = h.topics.Publish(r.Context(), topics[i])
+
+			// NOTE(toby3d): topic is repeatedly reported as removed by
+			// it's publisher, so there is nothing to distribute anymore.
+			if errors.Is(errs[i], topic.ErrGone) {
+				if _, err := h.hub.Retire(r.Context(), topics[i], domain.ErrReasonRetired); err != nil {
+					errs[i] = fmt.Errorf("%w: %w", errs[i], err)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	w.Header().Set(common.HeaderContentType, common.MIMETextPlainCharsetUTF8)
+	w.WriteHeader(http.StatusAccepted)
+
+	for i := range topics {
+		if errs[i] != nil {
+			fmt.Fprintf(w, "%s failed: %s\n", topics[i], errs[i])
+
+			continue
+		}
+
+		fmt.Fprintf(w, "%s accepted\n", topics[i])
+	}
+}
+
+// handleContent stores topic content sent by publisher in the request body
+// ("fat ping") instead of fetching it. The hub.mode and hub.topic parameters
+// are expected in the request query.
+func (h *Handler) handleContent(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if !query.Has(common.HubTopic) {
+		http.Error(w, fmt.Sprintf("%s parameter is required, but not provided", common.HubTopic),
+			http.StatusBadRequest)
+
+		return
+	}
+
+	u, err := url.Parse(query.Get(common.HubTopic))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot parse %s: %s", common.HubTopic, err), http.StatusBadRequest)
+
+		return
+	}
+
+	u = canonicalTopic(u, h.sortQuery)
+
+	if !h.authorize(w, r, ErrPublishContent, u) {
+		return
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxContentLength))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read topic content: %s", err), http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	if _, err = h.topics.PublishContent(r.Context(), domain.Topic{
+		Self:        u,
+		ContentType: r.Header.Get(common.HeaderContentType),
+		Content:     content,
+	}); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, topic.ErrHub) {
+			status = http.StatusUnprocessableEntity
+		}
+
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleRetire revokes all subscriptions of requested topics and purges their
+// content on behalf of authenticated publisher.
+func (h *Handler) handleRetire(w http.ResponseWriter, r *http.Request, req Request) {
+	if !h.authorize(w, r, ErrRetire, req.Topics...) {
+		return
+	}
+
+	w.Header().Set(common.HeaderContentType, common.MIMETextPlainCharsetUTF8)
+	w.WriteHeader(http.StatusAccepted)
+
+	for i := range req.Topics {
+		ok, err := h.hub.Retire(r.Context(), req.Topics[i], domain.ErrReasonRetired)
+
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "%s failed: %s\n", req.Topics[i], err)
+		case !ok:
+			fmt.Fprintf(w, "%s not found\n", req.Topics[i])
+		default:
+			fmt.Fprintf(w, "%s retired\n", req.Topics[i])
+		}
+	}
+}
+
+// handleReplay delivers the current or requested hub.version of topic content
+// once again to hub.callback subscriber, or to every topic subscriber if it's
+// omitted, on behalf of authenticated publisher.
+func (h *Handler) handleReplay(w http.ResponseWriter, r *http.Request, req Request) {
+	if !h.authorize(w, r, ErrReplay, req.Topic) {
+		return
+	}
+
+	count, err := h.hub.Replay(r.Context(), req.Topic, req.Callback, req.Version)
+	if err != nil {
+		if errors.Is(err, topic.ErrNotExist) || errors.Is(err, topic.ErrVersionNotExist) ||
+			errors.Is(err, subscription.ErrNotExist) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set(common.HeaderContentType, common.MIMETextPlainCharsetUTF8)
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "%s replayed to %d subscribers\n", req.Topic, count)
+}
+
+// authorize reports whether request is made by a trusted publisher which is
+// allowed to publish all of scope topics and prefixes, or responds with an
+// error otherwise. The disabled error is used if there is no trusted publishers
+// on this hub.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, disabled error, scope ...*url.URL) bool {
+	if h.publishToken == "" && h.publishers == nil {
+		http.Error(w, disabled.Error(), http.StatusForbidden)
+
+		return false
+	}
+
+	p, err := h.authenticate(r)
+	if err != nil {
+		if !errors.Is(err, publisher.ErrUnauthorized) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return false
+		}
+
+		w.Header().Set(common.HeaderWWWAuthenticate, `Bearer realm="publish"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+		return false
+	}
+
+	for i := range scope {
+		if !p.Allows(scope[i]) {
+			http.Error(w, fmt.Sprintf("publisher is not allowed to publish %s", scope[i]), http.StatusForbidden)
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// authenticate returns trusted publisher of request by it's bearer token or by
+// HMAC signature of the request body. Global publish token authenticates
+// publisher of any topics.
+func (h *Handler) authenticate(r *http.Request) (*domain.Publisher, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get(common.HeaderAuthorization), "Bearer "); ok {
+		if h.publishToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.publishToken)) == 1 {
+			return new(domain.Publisher), nil
+		}
+
+		if h.publishers == nil {
+			return nil, publisher.ErrUnauthorized
+		}
+
+		return h.publishers.Authenticate(r.Context(), token)
+	}
+
+	signature := r.Header.Get(common.HeaderXHubSignature)
+	if signature == "" || h.publishers == nil {
+		return nil, publisher.ErrUnauthorized
+	}
+
+	// NOTE(toby3d): signed timestamp limits the time in which intercepted
+	// request can be replayed.
+	timestamp := r.Header.Get(common.HeaderXHubTimestamp)
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s", publisher.ErrUnauthorized, common.HeaderXHubTimestamp)
+	}
+
+	if skew := time.Since(time.Unix(signedAt, 0)); skew > SignatureWindow || skew < -SignatureWindow {
+		return nil, fmt.Errorf("%w: signature is expired", publisher.ErrUnauthorized)
+	}
+
+	u := *r.URL
+	u.Host = r.Host
+	body, _ := r.Context().Value(rawBodyKey{}).([]byte)
+
+	return h.publishers.Verify(r.Context(), r.Header.Get(common.HeaderXHubPublisher), signature,
+		domain.SignedMessage(r.Method, &u, timestamp, body))
+}
+
+// intent verifies the intent of the subscriber with retries and applies
+// verified request. Subscriber will be notified if the subscription request is
+// denied.
+func (h *Handler) intent(ctx context.Context, s domain.Subscription, mode domain.Mode) {
+	var err error
+
+	for attempt := uint(1); ; attempt++ {
+		if err = h.verify(ctx, s, mode); err == nil {
+			break
+		}
+
+		// NOTE(toby3d): subscriber explicitly refused the verification,
+		// so there is no reason to retry it.
+		if errors.Is(err, hub.ErrNotFound) || errors.Is(err, hub.ErrChallenge) || attempt >= h.verifyAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(attempt) * h.verifyBackoff):
+		}
+	}
+
+	if err == nil {
+		switch mode {
+		case domain.ModeSubscribe:
+			_, err = h.subscriptions.Subscribe(ctx, s)
+		case domain.ModeUnsubscribe:
+			_, err = h.subscriptions.Unsubscribe(ctx, s)
+		}
+	}
+
+	if err == nil || mode != domain.ModeSubscribe {
+		return
+	}
+
+	h.notify(ctx, s.Callback, NewResponse(domain.Topic{Self: s.Topic}, err))
+}
+
+func (h *Handler) verify(ctx context.Context, s domain.Subscription, mode domain.Mode) error {
+	if h.verifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.verifyTimeout)
+
+		defer cancel()
+	}
+
+	_, err := h.hub.Verify(ctx, s, mode)
+
+	return err
+}
+
+// lease returns the number of seconds of subscription lease granted for the
+// requested one, clamped between the minimum and maximum leases of hub.
+func (h *Handler) lease(requested float64) float64 {
+	if limit := h.leaseMax.Seconds(); limit > 0 && requested > limit {
+		requested = limit
+	}
+
+	if limit := h.leaseMin.Seconds(); requested < limit {
+		requested = limit
+	}
+
+	return math.Round(requested)
+}
+
+// Shutdown cancels asynchronous verifications and waits until they return or
+// ctx is done.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		h.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("cannot wait for verifications: %w", ctx.Err())
+	case <-done:
+		return nil
+	}
+}
+
+// spawn runs fn asynchronously with context which is canceled by Shutdown. It
+// reports false without running fn if all verification workers are busy.
+func (h *Handler) spawn(fn func(ctx context.Context)) bool {
+	select {
+	case h.verifications <- struct{}{}:
+	default:
+		return false
+	}
+
+	h.wg.Add(1)
+
+	go func() {
+		defer func() {
+			<-h.verifications
+			h.wg.Done()
+		}()
+
+		fn(h.ctx)
+	}()
+
+	return true
+}
+
+// deny notifies subscriber about rejected subscription asynchronously. The
+// notification is dropped if all verification workers are busy.
+func (h *Handler) deny(callback *url.URL, resp *Response) {
+	_ = h.spawn(func(ctx context.Context) { h.notify(ctx, callback, resp) })
+}
+
+// notify sends denial resp to subscriber callback.
+func (h *Handler) notify(ctx context.Context, callback *url.URL, resp *Response) {
+	if h.verifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.verifyTimeout)
+
+		defer cancel()
+	}
+
+	_ = h.hub.Deny(ctx, callback, resp)
+}
+
+// notifiable reports whether subscriber of subscription request rejected by
+// reason may be notified about it. There is no reason to send anything to the
+// callback which is invalid or not allowed by policy, so policy is checked
+// before any denial request.
+func (h *Handler) notifiable(req Request, reason error) bool {
+	if req.Mode != domain.ModeSubscribe || req.Callback == nil || req.Topic == nil ||
+		errors.Is(reason, domain.ErrReasonCallback) {
+		return false
+	}
+
+	if h.policy == nil {
+		return true
+	}
+
+	return !errors.Is(h.policy.Check(req.Topic, req.Callback), domain.ErrReasonCallback)
+}
+
+// isContent reports whether request is a publish request with the topic content
+// in it's body.
+func isContent(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(common.HeaderContentType))
+	if mediaType == common.MIMEApplicationForm {
+		return false
+	}
+
+	return r.URL.Query().Get(common.HubMode) == domain.ModePublish.String()
+}
+
 func NewRequest() *Request {
 	return &Request{
 		Mode:         domain.ModeUnd,
@@ -134,7 +722,10 @@ func NewRequest() *Request {
 	}
 }
 
-func (r *Request) bind(req *http.Request) error {
+// bind parses request form into r. Topic and callback URLs are converted into
+// their canonical form, query parameters of topics are sorted if sortQuery is
+// true.
+func (r *Request) bind(req *http.Request, sortQuery bool) error {
 	var err error
 	if err = req.ParseForm(); err != nil {
 		return fmt.Errorf("cannot parse request form: %w", err)
@@ -149,18 +740,99 @@ func (r *Request) bind(req *http.Request) error {
 		return fmt.Errorf("cannot parse %s: %w", common.HubMode, err)
 	}
 
-	// NOTE(toby3d): hub.topic
-	if !req.PostForm.Has(common.HubTopic) {
-		return fmt.Errorf("%s parameter is required, but not provided", common.HubTopic)
-	}
+	switch r.Mode {
+	case domain.ModePublish, domain.ModeRetire:
+		// NOTE(toby3d): publishers may send multiple topics at once as
+		// repeated hub.topic or hub.url parameters, with or without
+		// array brackets.
+		for _, key := range []string{common.HubTopic, common.HubTopic + "[]", common.HubURL, common.HubURL + "[]"} {
+			for _, v := range req.PostForm[key] {
+				prefix, isPrefix := strings.CutSuffix(v, "*")
+
+				u, err := url.Parse(prefix)
+				if err != nil {
+					return fmt.Errorf("cannot parse %s: %w", key, err)
+				}
+
+				if !isPrefix {
+					u = canonicalTopic(u, sortQuery)
+
+					if !containsURL(r.Topics, u) {
+						r.Topics = append(r.Topics, u)
+					}
+
+					continue
+				}
+
+				if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return fmt.Errorf("%s prefix MUST contain scheme and host: %s", key, v)
+				}
+
+				u = urlutil.Canonical(u)
+
+				if !containsURL(r.Prefixes, u) {
+					r.Prefixes = append(r.Prefixes, u)
+				}
+			}
+		}
 
-	if r.Topic, err = url.Parse(req.PostForm.Get(common.HubTopic)); err != nil {
-		return fmt.Errorf("cannot parse %s: %w", common.HubTopic, err)
-	}
+		if r.Mode == domain.ModeRetire && len(r.Prefixes) > 0 {
+			return fmt.Errorf("topics cannot be retired by prefix")
+		}
 
-	switch r.Mode {
-	case domain.ModePublish:
+		if len(r.Topics)+len(r.Prefixes) == 0 {
+			return fmt.Errorf("%s parameter is required, but not provided", common.HubTopic)
+		}
+
+		if len(r.Topics)+len(r.Prefixes) > MaxRequestTopics {
+			return fmt.Errorf("too many topics in a single request: got %d, want %d or less",
+				len(r.Topics)+len(r.Prefixes), MaxRequestTopics)
+		}
+
+		if len(r.Topics) > 0 {
+			r.Topic = r.Topics[0]
+		}
+	case domain.ModeReplay:
+		// NOTE(toby3d): hub.topic
+		if !req.PostForm.Has(common.HubTopic) {
+			return fmt.Errorf("%s parameter is required, but not provided", common.HubTopic)
+		}
+
+		if r.Topic, err = url.Parse(req.PostForm.Get(common.HubTopic)); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", common.HubTopic, err)
+		}
+
+		r.Topic = canonicalTopic(r.Topic, sortQuery)
+		r.Topics = []*url.URL{r.Topic}
+
+		// NOTE(toby3d): hub.callback
+		if req.PostForm.Has(common.HubCallback) {
+			if r.Callback, err = url.Parse(req.PostForm.Get(common.HubCallback)); err != nil {
+				return fmt.Errorf("cannot parse %s: %w", common.HubCallback, err)
+			}
+
+			r.Callback = urlutil.Canonical(r.Callback)
+		}
+
+		// NOTE(toby3d): hub.version
+		if req.PostForm.Has(common.HubVersion) {
+			if r.Version, err = strconv.ParseUint(req.PostForm.Get(common.HubVersion), 10, 64); err != nil {
+				return fmt.Errorf("cannot parse %s: %w", common.HubVersion, err)
+			}
+		}
 	case domain.ModeSubscribe, domain.ModeUnsubscribe:
+		// NOTE(toby3d): hub.topic
+		if !req.PostForm.Has(common.HubTopic) {
+			return fmt.Errorf("%s parameter is required, but not provided", common.HubTopic)
+		}
+
+		if r.Topic, err = url.Parse(req.PostForm.Get(common.HubTopic)); err != nil {
+			return fmt.Errorf("cannot parse %s: %w", common.HubTopic, err)
+		}
+
+		r.Topic = canonicalTopic(r.Topic, sortQuery)
+		r.Topics = []*url.URL{r.Topic}
+
 		// NOTE(toby3d): hub.callback
 		if !req.PostForm.Has(common.HubCallback) {
 			return fmt.Errorf("%s parameter is required, but not provided", common.HubCallback)
@@ -170,18 +842,38 @@ func (r *Request) bind(req *http.Request) error {
 			return fmt.Errorf("cannot parse %s: %w", common.HubCallback, err)
 		}
 
+		if (r.Callback.Scheme != "http" && r.Callback.Scheme != "https") || r.Callback.Host == "" {
+			return fmt.Errorf("%w: %s", domain.ErrReasonCallback, r.Callback)
+		}
+
+		r.Callback = urlutil.Canonical(r.Callback)
+
 		// NOTE(toby3d): hub.lease_seconds
 		if r.Mode != domain.ModeUnsubscribe && req.PostForm.Has(common.HubLeaseSeconds) {
 			r.LeaseSeconds, err = strconv.ParseFloat(req.PostForm.Get(common.HubLeaseSeconds), 64)
 			if err != nil {
 				return fmt.Errorf("cannot parse %s: %w", common.HubLeaseSeconds, err)
 			}
+
+			if math.IsNaN(r.LeaseSeconds) || math.IsInf(r.LeaseSeconds, 0) {
+				return fmt.Errorf("%s MUST be a number of seconds", common.HubLeaseSeconds)
+			}
+		}
+
+		// NOTE(toby3d): hub.content
+		switch content := req.PostForm.Get(common.HubContent); content {
+		case "", ContentDiff:
+		case ContentFull:
+			r.Full = true
+		default:
+			return fmt.Errorf("%s MUST be %s or %s, got %s", common.HubContent, ContentDiff, ContentFull,
+				content)
 		}
 
 		// NOTE(toby3d): hub.secret
 		if !req.PostForm.Has(common.HubSecret) {
 			if req.TLS != nil {
-				return ErrHubSecret
+				return fmt.Errorf("%w: %w", domain.ErrReasonSecret, ErrHubSecret)
 			}
 
 			return nil
@@ -189,7 +881,7 @@ func (r *Request) bind(req *http.Request) error {
 
 		secret, err := domain.ParseSecret(req.PostForm.Get(common.HubSecret))
 		if err != nil {
-			return fmt.Errorf("cannot parse %s: %w", common.HubSecret, err)
+			return fmt.Errorf("cannot parse %s: %w: %w", common.HubSecret, domain.ErrReasonSecret, err)
 		}
 
 		r.Secret = *secret
@@ -205,17 +897,41 @@ func (r Request) populate(s *domain.Subscription, ts time.Time) {
 	s.Callback = r.Callback
 	s.Topic = r.Topic
 	s.Secret = r.Secret
+	s.Full = r.Full
+}
+
+// canonicalTopic returns the canonical form of topic URL, with sorted query
+// parameters if sortQuery is true.
+func canonicalTopic(u *url.URL, sortQuery bool) *url.URL {
+	u = urlutil.Canonical(u)
+	if sortQuery {
+		u = urlutil.SortQuery(u)
+	}
+
+	return u
+}
+
+func containsURL(list []*url.URL, u *url.URL) bool {
+	for i := range list {
+		if list[i].String() == u.String() {
+			return true
+		}
+	}
+
+	return false
 }
 
+// NewResponse creates a denial notification of subscription to topic which
+// was rejected because of err.
 func NewResponse(t domain.Topic, err error) *Response {
 	return &Response{
 		Mode:   domain.ModeDenied,
 		Topic:  t,
-		Reason: err.Error(),
+		Reason: domain.Reason(err).Error(),
 	}
 }
 
-func (r *Response) populate(q url.Values) {
+func (r *Response) AddQuery(q url.Values) {
 	r.Mode.AddQuery(q)
 	r.Topic.AddQuery(q)
 	q.Add(common.HubReason, r.Reason)
@@ -2,12 +2,18 @@ package http_test
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/text/language"
 
@@ -15,6 +21,10 @@ import (
 	"source.toby3d.me/toby3d/hub/internal/domain"
 	delivery "source.toby3d.me/toby3d/hub/internal/hub/delivery/http"
 	hubucase "source.toby3d.me/toby3d/hub/internal/hub/usecase"
+	"source.toby3d.me/toby3d/hub/internal/policy"
+	publishermemoryrepo "source.toby3d.me/toby3d/hub/internal/publisher/repository/memory"
+	publisherucase "source.toby3d.me/toby3d/hub/internal/publisher/usecase"
+	queuememoryrepo "source.toby3d.me/toby3d/hub/internal/queue/repository/memory"
 	subscriptionmemoryrepo "source.toby3d.me/toby3d/hub/internal/subscription/repository/memory"
 	subscriptionucase "source.toby3d.me/toby3d/hub/internal/subscription/usecase"
 	topicmemoryrepo "source.toby3d.me/toby3d/hub/internal/topic/repository/memory"
@@ -32,10 +42,12 @@ func TestHandler_ServeHTTP_Subscribe(t *testing.T) {
 	in := domain.TestSubscription(t, srv.URL+"/lipsum")
 	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
 	topics := topicmemoryrepo.NewMemoryTopicRepository()
-	hub := hubucase.NewHubUseCase(topics, subscriptions, srv.Client(), &url.URL{
-		Scheme: "https",
-		Host:   "hub.exmaple.com",
-		Path:   "/",
+	hub := hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        domain.TestConfig(t),
 	})
 
 	payload := make(url.Values)
@@ -47,11 +59,15 @@ func TestHandler_ServeHTTP_Subscribe(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	delivery.NewHandler(delivery.NewHandlerParams{
-		Hub:           hub,
-		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptions, topics, srv.Client()),
-		Topics:        topicucase.NewTopicUseCase(topics, srv.Client()),
-		Matcher:       language.NewMatcher([]language.Tag{language.English}),
-		Name:          "WebSub",
+		Hub: hub,
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:  topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher: language.NewMatcher([]language.Tag{language.English}),
+		Name:    "WebSub",
 	}).ServeHTTP(w, req)
 
 	resp := w.Result()
@@ -78,10 +94,12 @@ func TestHandler_ServeHTTP_Unsubscribe(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	hub := hubucase.NewHubUseCase(topics, subscriptions, srv.Client(), &url.URL{
-		Scheme: "https",
-		Host:   "hub.exmaple.com",
-		Path:   "/",
+	hub := hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        domain.TestConfig(t),
 	})
 
 	payload := make(url.Values)
@@ -93,11 +111,15 @@ func TestHandler_ServeHTTP_Unsubscribe(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	delivery.NewHandler(delivery.NewHandlerParams{
-		Hub:           hub,
-		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptions, topics, srv.Client()),
-		Topics:        topicucase.NewTopicUseCase(topics, srv.Client()),
-		Matcher:       language.NewMatcher([]language.Tag{language.English}),
-		Name:          "WebSub",
+		Hub: hub,
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:  topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher: language.NewMatcher([]language.Tag{language.English}),
+		Name:    "WebSub",
 	}).ServeHTTP(w, req)
 
 	resp := w.Result()
@@ -106,3 +128,941 @@ func TestHandler_ServeHTTP_Unsubscribe(t *testing.T) {
 		t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
 	}
 }
+
+func TestHandler_ServeHTTP_Denied(t *testing.T) {
+	t.Parallel()
+
+	denied := make(chan url.Values, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		// NOTE(toby3d): reply with a wrong challenge to fail the
+		// verification of intent.
+		if q.Get(common.HubMode) != domain.ModeDenied.String() {
+			fmt.Fprint(w, "not a challenge")
+
+			return
+		}
+
+		denied <- q
+	}))
+	t.Cleanup(srv.Close)
+
+	in := domain.TestSubscription(t, srv.URL+"/lipsum")
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	config := domain.TestConfig(t)
+	hub := hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        config,
+	})
+
+	payload := make(url.Values)
+	domain.ModeSubscribe.AddQuery(payload)
+	in.AddQuery(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/", strings.NewReader(payload.Encode()))
+	req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+	w := httptest.NewRecorder()
+	delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hub,
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:         topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher:        language.NewMatcher([]language.Tag{language.English}),
+		Name:           "WebSub",
+		VerifyAttempts: config.VerifyAttempts,
+		VerifyTimeout:  config.VerifyTimeout,
+		VerifyBackoff:  config.VerifyBackoff,
+	}).ServeHTTP(w, req)
+
+	resp := w.Result()
+
+	if expect := http.StatusAccepted; resp.StatusCode != expect {
+		t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
+	}
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not notified about denied subscription")
+	case q := <-denied:
+		if actual := q.Get(common.HubTopic); actual != in.Topic.String() {
+			t.Errorf("want '%s', got '%s'", in.Topic, actual)
+		}
+
+		if q.Get(common.HubReason) == "" {
+			t.Errorf("want non-empty %s", common.HubReason)
+		}
+	}
+}
+
+func TestHandler_ServeHTTP_Lease(t *testing.T) {
+	t.Parallel()
+
+	config := domain.TestConfig(t)
+
+	for name, tc := range map[string]struct {
+		input  string
+		expect time.Duration
+	}{
+		"default":  {input: "", expect: config.LeaseDefault},
+		"accepted": {input: "3600", expect: time.Hour},
+		"short":    {input: "1", expect: config.LeaseMin},
+		"negative": {input: "-3600", expect: config.LeaseMin},
+		"long":     {input: "31536000", expect: config.LeaseMax},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			leases := make(chan string, 1)
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, r.URL.Query().Get(common.HubChallenge))
+
+				leases <- r.URL.Query().Get(common.HubLeaseSeconds)
+			}))
+			t.Cleanup(srv.Close)
+
+			in := domain.TestSubscription(t, srv.URL+"/lipsum")
+			subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+			hub := hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+				Topics:        topics,
+				Subscriptions: subscriptions,
+				Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+				Client:        srv.Client(),
+				Config:        config,
+			})
+
+			payload := make(url.Values)
+			domain.ModeSubscribe.AddQuery(payload)
+			in.AddQuery(payload)
+			payload.Del(common.HubLeaseSeconds)
+
+			if tc.input != "" {
+				payload.Set(common.HubLeaseSeconds, tc.input)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/",
+				strings.NewReader(payload.Encode()))
+			req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+			w := httptest.NewRecorder()
+			delivery.NewHandler(delivery.NewHandlerParams{
+				Hub: hub,
+				Subscriptions: subscriptionucase.NewSubscriptionUseCase(
+					subscriptionucase.NewSubscriptionUseCaseParams{
+						Subscriptions: subscriptions,
+						Topics:        topics,
+						Client:        srv.Client(),
+					}),
+				Topics: topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{
+					Topics: topics,
+					Client: srv.Client(),
+				}),
+				Matcher:        language.NewMatcher([]language.Tag{language.English}),
+				Name:           "WebSub",
+				VerifyAttempts: config.VerifyAttempts,
+				VerifyTimeout:  config.VerifyTimeout,
+				VerifyBackoff:  config.VerifyBackoff,
+				LeaseMin:       config.LeaseMin,
+				LeaseMax:       config.LeaseMax,
+				LeaseDefault:   config.LeaseDefault,
+			}).ServeHTTP(w, req)
+
+			if resp := w.Result(); resp.StatusCode != http.StatusAccepted {
+				t.Fatalf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode,
+					http.StatusAccepted)
+			}
+
+			select {
+			case <-time.After(5 * time.Second):
+				t.Fatal("subscriber was not asked to verify subscription")
+			case actual := <-leases:
+				if expect := fmt.Sprintf("%.0f", tc.expect.Seconds()); actual != expect {
+					t.Errorf("want %s lease, got %s", expect, actual)
+				}
+			}
+
+			// NOTE(toby3d): subscription is stored after the
+			// verification response is received.
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+				s, err := subscriptions.Get(context.Background(), in.SUID())
+				if err != nil {
+					time.Sleep(10 * time.Millisecond)
+
+					continue
+				}
+
+				if actual := s.ExpiredAt.Sub(s.UpdatedAt); actual != tc.expect {
+					t.Errorf("want %s lease, got %s", tc.expect, actual)
+				}
+
+				return
+			}
+
+			t.Error("subscription was not stored after verification")
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		callbackDeny []string
+		notified     bool
+	}{
+		"allowed callback": {notified: true},
+		"denied callback":  {callbackDeny: []string{"127.0.0.1"}},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			denied := make(chan url.Values, 1)
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				denied <- r.URL.Query()
+			}))
+			t.Cleanup(srv.Close)
+
+			rules, err := policy.New(nil, nil, nil, tc.callbackDeny)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			in := domain.TestSubscription(t, srv.URL+"/lipsum")
+			subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+
+			payload := make(url.Values)
+			domain.ModeSubscribe.AddQuery(payload)
+			in.AddQuery(payload)
+			payload.Set(common.HubLeaseSeconds, "lipsum")
+
+			req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/",
+				strings.NewReader(payload.Encode()))
+			req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+			w := httptest.NewRecorder()
+			delivery.NewHandler(delivery.NewHandlerParams{
+				Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+					Topics:        topics,
+					Subscriptions: subscriptions,
+					Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+					Client:        srv.Client(),
+					Config:        domain.TestConfig(t),
+				}),
+				Subscriptions: subscriptionucase.NewSubscriptionUseCase(
+					subscriptionucase.NewSubscriptionUseCaseParams{
+						Subscriptions: subscriptions,
+						Topics:        topics,
+						Client:        srv.Client(),
+					}),
+				Topics: topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{
+					Topics: topics,
+					Client: srv.Client(),
+				}),
+				Matcher: language.NewMatcher([]language.Tag{language.English}),
+				Name:    "WebSub",
+				Policy:  policy.NewEngine(rules),
+			}).ServeHTTP(w, req)
+
+			resp := w.Result()
+
+			if expect := http.StatusBadRequest; resp.StatusCode != expect {
+				t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
+			}
+
+			// NOTE(toby3d): callback which is not allowed by policy
+			// must not receive any request.
+			select {
+			case <-time.After(time.Second):
+				if tc.notified {
+					t.Error("subscriber was not notified about invalid subscription")
+				}
+			case q := <-denied:
+				if !tc.notified {
+					t.Errorf("want no requests to denied callback, got %v", q)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	requests := make(chan url.Values, 10)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.Query()
+
+		// NOTE(toby3d): reply with a server error to make the
+		// verification of intent wait for the next attempt.
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	in := domain.TestSubscription(t, srv.URL+"/lipsum")
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        srv.Client(),
+			Config:        domain.TestConfig(t),
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:         topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher:        language.NewMatcher([]language.Tag{language.English}),
+		Name:           "WebSub",
+		VerifyAttempts: 10,
+		VerifyTimeout:  time.Second,
+		VerifyBackoff:  time.Hour,
+		VerifyWorkers:  1,
+	})
+
+	payload := make(url.Values)
+	domain.ModeSubscribe.AddQuery(payload)
+	in.AddQuery(payload)
+
+	for i, expect := range []int{http.StatusAccepted, http.StatusServiceUnavailable} {
+		req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/", strings.NewReader(payload.Encode()))
+		req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if resp := w.Result(); resp.StatusCode != expect {
+			t.Fatalf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
+		}
+
+		if i > 0 {
+			continue
+		}
+
+		// NOTE(toby3d): wait for the first failed attempt, after which
+		// the only verification worker is waiting for the next one.
+		select {
+		case <-requests:
+		case <-time.After(5 * time.Second):
+			t.Fatal("verification request was not received")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	start := time.Now()
+
+	if err := handler.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Shutdown() took %s, want less than %s", elapsed, time.Second)
+	}
+
+	// NOTE(toby3d): canceled verification is neither retried nor applied.
+	if len(requests) != 0 {
+		t.Errorf("got %d requests to callback after shutdown, want 0", len(requests))
+	}
+
+	if _, err := subscriptions.Get(context.Background(), in.SUID()); err == nil {
+		t.Error("canceled verification of intent is applied")
+	}
+}
+
+func TestHandler_ServeHTTP_Policy(t *testing.T) {
+	t.Parallel()
+
+	denied := make(chan url.Values, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		denied <- r.URL.Query()
+	}))
+	t.Cleanup(srv.Close)
+
+	in := domain.TestSubscription(t, srv.URL+"/lipsum")
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+
+	rules, err := policy.New([]string{"example.org"}, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make(url.Values)
+	domain.ModeSubscribe.AddQuery(payload)
+	in.AddQuery(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/", strings.NewReader(payload.Encode()))
+	req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+	w := httptest.NewRecorder()
+	delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        srv.Client(),
+			Config:        domain.TestConfig(t),
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:  topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher: language.NewMatcher([]language.Tag{language.English}),
+		Name:    "WebSub",
+		Policy:  policy.NewEngine(rules),
+	}).ServeHTTP(w, req)
+
+	resp := w.Result()
+
+	if expect := http.StatusForbidden; resp.StatusCode != expect {
+		t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
+	}
+
+	select {
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber was not notified about denied subscription")
+	case q := <-denied:
+		if q.Get(common.HubMode) != domain.ModeDenied.String() {
+			t.Errorf("want %s mode, got %s", domain.ModeDenied, q.Get(common.HubMode))
+		}
+
+		if actual := q.Get(common.HubReason); actual != domain.ErrReasonPolicy.Error() {
+			t.Errorf("want '%s', got '%s'", domain.ErrReasonPolicy, actual)
+		}
+	}
+
+	if _, err = subscriptions.Get(context.Background(), in.SUID()); err == nil {
+		t.Error("subscription must not be created")
+	}
+}
+
+func TestHandler_ServeHTTP_PublishContent(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	config := domain.TestConfig(t)
+	client := http.DefaultClient
+
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        client,
+			Config:        config,
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        client,
+		}),
+		Topics:       topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: client}),
+		Matcher:      language.NewMatcher([]language.Tag{language.English}),
+		Name:         "WebSub",
+		PublishToken: config.PublishToken,
+	})
+
+	query := make(url.Values)
+	domain.ModePublish.AddQuery(query)
+	topic.AddQuery(query)
+
+	for name, tc := range map[string]struct {
+		token  string
+		expect int
+	}{
+		"unauthorized": {token: "", expect: http.StatusUnauthorized},
+		"invalid":      {token: "invalid", expect: http.StatusUnauthorized},
+		"accepted":     {token: config.PublishToken, expect: http.StatusAccepted},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/?"+query.Encode(),
+				strings.NewReader(string(topic.Content)))
+			req.Header.Set(common.HeaderContentType, topic.ContentType)
+
+			if tc.token != "" {
+				req.Header.Set(common.HeaderAuthorization, "Bearer "+tc.token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+
+			if resp.StatusCode != tc.expect {
+				t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, tc.expect)
+			}
+
+			if resp.StatusCode != http.StatusAccepted {
+				return
+			}
+
+			actual, err := topics.Get(context.Background(), topic.Self)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(actual.Content) != string(topic.Content) {
+				t.Errorf("want '%s', got '%s'", topic.Content, actual.Content)
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_PublishAuth(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(common.HeaderContentType, common.MIMETextPlainCharsetUTF8)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	publishers := publisherucase.NewPublisherUseCase(publisherucase.NewPublisherUseCaseParams{
+		Publishers: publishermemoryrepo.NewMemoryPublisherRepository(),
+	})
+
+	scope, _ := url.Parse(srv.URL + "/allowed/")
+
+	p, token, err := publishers.Register(context.Background(), []*url.URL{scope})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        srv.Client(),
+			Config:        domain.TestConfig(t),
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:      topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher:     language.NewMatcher([]language.Tag{language.English}),
+		Name:        "WebSub",
+		Publishers:  publishers,
+		PublishAuth: true,
+	})
+
+	sign := func(rawURL string, signedAt time.Time, body string) http.Header {
+		u, _ := url.Parse(rawURL)
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+
+		h := hmac.New(sha256.New, []byte(p.Secret.String()))
+		h.Write(domain.SignedMessage(http.MethodPost, u, timestamp, []byte(body)))
+
+		return http.Header{
+			common.HeaderXHubPublisher: {p.ID},
+			common.HeaderXHubSignature: {"sha256=" + hex.EncodeToString(h.Sum(nil))},
+			common.HeaderXHubTimestamp: {timestamp},
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		path   string
+		header func(body string) http.Header
+		expect int
+	}{
+		"anonymous": {path: "/allowed/feed", expect: http.StatusUnauthorized},
+		"invalid": {
+			path:   "/allowed/feed",
+			header: func(string) http.Header { return http.Header{common.HeaderAuthorization: {"Bearer invalid"}} },
+			expect: http.StatusUnauthorized,
+		},
+		"token": {
+			path:   "/allowed/feed",
+			header: func(string) http.Header { return http.Header{common.HeaderAuthorization: {"Bearer " + token}} },
+			expect: http.StatusAccepted,
+		},
+		"scope": {
+			path:   "/denied/feed",
+			header: func(string) http.Header { return http.Header{common.HeaderAuthorization: {"Bearer " + token}} },
+			expect: http.StatusForbidden,
+		},
+		"signature": {
+			path: "/allowed/feed",
+			header: func(body string) http.Header {
+				return sign("https://hub.example.com/", time.Now(), body)
+			},
+			expect: http.StatusAccepted,
+		},
+		"expired": {
+			path: "/allowed/feed",
+			header: func(body string) http.Header {
+				return sign("https://hub.example.com/", time.Now().Add(-1*time.Hour), body)
+			},
+			expect: http.StatusUnauthorized,
+		},
+		"url": {
+			path: "/allowed/feed",
+			header: func(body string) http.Header {
+				return sign("https://hub.example.com/?hub.mode=publish", time.Now(), body)
+			},
+			expect: http.StatusUnauthorized,
+		},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			payload := make(url.Values)
+			domain.ModePublish.AddQuery(payload)
+			payload.Add(common.HubTopic, srv.URL+tc.path)
+			body := payload.Encode()
+
+			req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/", strings.NewReader(body))
+			if tc.header != nil {
+				req.Header = tc.header(body)
+			}
+
+			req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if resp := w.Result(); resp.StatusCode != tc.expect {
+				t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, tc.expect)
+			}
+		})
+	}
+}
+
+func TestHandler_ServeHTTP_PublishMultiple(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(common.HeaderContentType, common.MIMETextPlainCharsetUTF8)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+
+	payload := make(url.Values)
+	domain.ModePublish.AddQuery(payload)
+	payload.Add(common.HubURL+"[]", srv.URL+"/foo")
+	payload.Add(common.HubURL+"[]", srv.URL+"/bar")
+	payload.Add(common.HubTopic, srv.URL+"/baz")
+	payload.Add(common.HubURL, srv.URL+"/foo")
+
+	req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/", strings.NewReader(payload.Encode()))
+	req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+	w := httptest.NewRecorder()
+	delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        srv.Client(),
+			Config:        domain.TestConfig(t),
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:  topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher: language.NewMatcher([]language.Tag{language.English}),
+		Name:    "WebSub",
+	}).ServeHTTP(w, req)
+
+	resp := w.Result()
+
+	if expect := http.StatusAccepted; resp.StatusCode != expect {
+		t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, expect)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, path := range []string{"/foo", "/bar", "/baz"} {
+		u, _ := url.Parse(srv.URL + path)
+
+		if _, err := topics.Get(context.Background(), u); err != nil {
+			t.Errorf("%s: %s", u, err)
+		}
+
+		if expect := u.String() + " accepted\n"; !strings.Contains(string(body), expect) {
+			t.Errorf("want '%s' in response body, got '%s'", expect, body)
+		}
+	}
+
+	if count := strings.Count(string(body), "\n"); count != 3 {
+		t.Errorf("want %d results, got %d", 3, count)
+	}
+}
+
+func TestHandler_ServeHTTP_PublishPrefix(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(common.HeaderContentType, common.MIMETextPlainCharsetUTF8)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+
+	for _, path := range []string{"/blog/foo", "/blog/bar", "/about"} {
+		topic := domain.TestTopic(t)
+		topic.Self, _ = url.Parse(srv.URL + path)
+		topic.Content = nil
+
+		if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := domain.TestConfig(t)
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        srv.Client(),
+			Config:        config,
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        srv.Client(),
+		}),
+		Topics:             topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: srv.Client()}),
+		Matcher:            language.NewMatcher([]language.Tag{language.English}),
+		Name:               "WebSub",
+		PublishToken:       config.PublishToken,
+		PublishPrefixLimit: config.PublishPrefixLimit,
+	})
+
+	payload := make(url.Values)
+	domain.ModePublish.AddQuery(payload)
+	payload.Add(common.HubURL, srv.URL+"/blog/*")
+
+	// NOTE(toby3d): cases depends from each other, so run them in order.
+	for _, tc := range []struct {
+		token  string
+		expect int
+	}{
+		{token: "", expect: http.StatusUnauthorized},
+		{token: config.PublishToken, expect: http.StatusAccepted},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/",
+			strings.NewReader(payload.Encode()))
+		req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+		if tc.token != "" {
+			req.Header.Set(common.HeaderAuthorization, "Bearer "+tc.token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if resp := w.Result(); resp.StatusCode != tc.expect {
+			t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, tc.expect)
+		}
+	}
+
+	for path, expect := range map[string]string{
+		"/blog/foo": "/blog/foo",
+		"/blog/bar": "/blog/bar",
+		"/about":    "",
+	} {
+		u, _ := url.Parse(srv.URL + path)
+
+		topic, err := topics.Get(context.Background(), u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(topic.Content) != expect {
+			t.Errorf("want '%s', got '%s'", expect, topic.Content)
+		}
+	}
+}
+
+func TestHandler_ServeHTTP_Retire(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	config := domain.TestConfig(t)
+	client := http.DefaultClient
+
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+			Client:        client,
+			Config:        config,
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        client,
+		}),
+		Topics:       topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: client}),
+		Matcher:      language.NewMatcher([]language.Tag{language.English}),
+		Name:         "WebSub",
+		PublishToken: config.PublishToken,
+	})
+
+	payload := make(url.Values)
+	domain.ModeRetire.AddQuery(payload)
+	topic.AddQuery(payload)
+
+	// NOTE(toby3d): cases depends from each other, so run them in order.
+	for _, tc := range []struct {
+		token  string
+		expect int
+	}{
+		{token: "", expect: http.StatusUnauthorized},
+		{token: config.PublishToken, expect: http.StatusAccepted},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/",
+			strings.NewReader(payload.Encode()))
+		req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+		if tc.token != "" {
+			req.Header.Set(common.HeaderAuthorization, "Bearer "+tc.token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if resp := w.Result(); resp.StatusCode != tc.expect {
+			t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, tc.expect)
+		}
+	}
+
+	if _, err := topics.Get(context.Background(), topic.Self); err == nil {
+		t.Error("want purged retired topic, got exists")
+	}
+}
+
+func TestHandler_ServeHTTP_Replay(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	jobs := queuememoryrepo.NewMemoryQueueRepository()
+	config := domain.TestConfig(t)
+	client := http.DefaultClient
+
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := domain.TestSubscription(t, "https://example.net/callback")
+	subscription.Topic = topic.Self
+	subscription.SyncedVersionID = topic.VersionID
+
+	if err := subscriptions.Create(context.Background(), subscription.SUID(), *subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := delivery.NewHandler(delivery.NewHandlerParams{
+		Hub: hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+			Topics:        topics,
+			Subscriptions: subscriptions,
+			Queue:         jobs,
+			Client:        client,
+			Config:        config,
+		}),
+		Subscriptions: subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+			Subscriptions: subscriptions,
+			Topics:        topics,
+			Client:        client,
+		}),
+		Topics:       topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{Topics: topics, Client: client}),
+		Matcher:      language.NewMatcher([]language.Tag{language.English}),
+		Name:         "WebSub",
+		PublishToken: config.PublishToken,
+	})
+
+	payload := make(url.Values)
+	domain.ModeReplay.AddQuery(payload)
+	topic.AddQuery(payload)
+	payload.Set(common.HubCallback, subscription.Callback.String())
+
+	for _, tc := range []struct {
+		token   string
+		version string
+		expect  int
+	}{
+		{token: "", expect: http.StatusUnauthorized},
+		{token: config.PublishToken, version: "2", expect: http.StatusNotFound},
+		{token: config.PublishToken, expect: http.StatusAccepted},
+	} {
+		payload.Del(common.HubVersion)
+		if tc.version != "" {
+			payload.Set(common.HubVersion, tc.version)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "https://hub.example.com/",
+			strings.NewReader(payload.Encode()))
+		req.Header.Set(common.HeaderContentType, common.MIMEApplicationFormCharsetUTF8)
+
+		if tc.token != "" {
+			req.Header.Set(common.HeaderAuthorization, "Bearer "+tc.token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if resp := w.Result(); resp.StatusCode != tc.expect {
+			t.Errorf("%s %s = %d, want %d", req.Method, req.RequestURI, resp.StatusCode, tc.expect)
+		}
+	}
+
+	j, err := jobs.Get(context.Background(), subscription.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !j.Replay || j.VersionID != topic.VersionID {
+		t.Errorf("want replay of %d version, got %+v", topic.VersionID, j)
+	}
+}
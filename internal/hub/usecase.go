@@ -3,12 +3,27 @@ package hub
 import (
 	"context"
 	"errors"
+	"net/url"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
 )
 
 type UseCase interface {
 	Verify(ctx context.Context, subscription domain.Subscription, mode domain.Mode) (bool, error)
+	// Deny notifies subscriber callback that it's subscription request
+	// was denied by hub.
+	Deny(ctx context.Context, callback *url.URL, params domain.QueryAdder) error
+	// Revoke removes existing subscription and notifies it's subscriber
+	// with reason.
+	Revoke(ctx context.Context, suid domain.SUID, reason error) (bool, error)
+	// Retire revokes all subscriptions of topic with reason and purges
+	// it's content.
+	Retire(ctx context.Context, u *url.URL, reason error) (bool, error)
+	// Replay schedules delivery of the topic content version, or the
+	// current one if version is zero, to subscriber callback once again,
+	// or to every topic subscriber if callback is nil. It returns the
+	// number of scheduled deliveries.
+	Replay(ctx context.Context, u, callback *url.URL, version uint64) (int, error)
 	ListenAndServe(ctx context.Context) error
 }
 
@@ -16,4 +31,5 @@ var (
 	ErrStatus    = errors.New("subscriber replied with a non 2xx status")
 	ErrNotFound  = errors.New("subscriber denied verification, responding with a 404 status")
 	ErrChallenge = errors.New("the challenge of the hub and the subscriber do not match")
+	ErrNotify    = errors.New("cannot notify subscriber")
 )
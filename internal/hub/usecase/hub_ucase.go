@@ -5,39 +5,127 @@ import (
 	"context"
 	"crypto/hmac"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/common"
 	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/feed"
+	"source.toby3d.me/toby3d/hub/internal/httputil"
 	"source.toby3d.me/toby3d/hub/internal/hub"
+	"source.toby3d.me/toby3d/hub/internal/queue"
 	"source.toby3d.me/toby3d/hub/internal/subscription"
 	"source.toby3d.me/toby3d/hub/internal/topic"
 )
 
-type hubUseCase struct {
-	subscriptions subscription.Repository
-	topics        topic.Repository
-	client        *http.Client
-	self          *url.URL
-}
+type (
+	NewHubUseCaseParams struct {
+		Topics        topic.Repository
+		Subscriptions subscription.Repository
+		Queue         queue.Repository
+		Client        *http.Client
+		Config        *domain.Config
+		// Updates wakes up distribution of published topics, if not nil.
+		Updates <-chan domain.Topic
+		// Publisher polls topics which are scheduled for polling, if
+		// not nil.
+		Publisher topic.UseCase
+		// Deliveries logs every content distribution attempt, if not
+		// nil.
+		Deliveries subscription.DeliveryRepository
+		// Versions are replayed by request and purged with retired
+		// topics, if not nil.
+		Versions topic.VersionRepository
+		// Logger reports failed delivery jobs, if not nil.
+		Logger *log.Logger
+	}
+
+	// response is an outcome of content distribution request.
+	response struct {
+		// Truncated response body
+		body    string
+		latency time.Duration
+		// Delay which subscriber asked to retry after
+		retry  time.Duration
+		status int
+		// Request was sent to subscriber
+		sent bool
+	}
+
+	// denial is a hub.mode=denied notification of existing subscription.
+	denial struct {
+		topic  *url.URL
+		reason error
+	}
+
+	hubUseCase struct {
+		// Topics updating time which is already scheduled
+		scheduledAt time.Time
+		// Time of the last deliveries history pruning
+		prunedAt time.Time
+
+		subscriptions subscription.Repository
+		topics        topic.Repository
+		queue         queue.Repository
+		client        *http.Client
+		config        *domain.Config
+		updates       <-chan domain.Topic
+		limiter       *limiter
+		publisher     topic.UseCase
+		deliveries    subscription.DeliveryRepository
+		versions      topic.VersionRepository
+		logger        *log.Logger
+		// Semaphore of concurrent topics polls
+		polls chan struct{}
+		// Semaphore of concurrent subscriptions renewals
+		renewals chan struct{}
+	}
+)
 
 const (
 	lengthMin = 16
 	lengthMax = 32
 )
 
-func NewHubUseCase(t topic.Repository, s subscription.Repository, c *http.Client, u *url.URL) hub.UseCase {
+// MaxDeliveryBody is a maximum size of subscriber response body which is stored
+// in deliveries history.
+const MaxDeliveryBody int64 = 1 << 10 // 1 KiB
+
+// pruneInterval is a minimum interval between deliveries history prunings.
+const pruneInterval time.Duration = time.Hour
+
+// renewWorkers is a maximum number of concurrent automatic renewals of
+// subscriptions.
+const renewWorkers int = 4
+
+func NewHubUseCase(params NewHubUseCaseParams) hub.UseCase {
+	pollWorkers := params.Config.PollWorkers
+	if pollWorkers == 0 {
+		pollWorkers = 1
+	}
+
 	return &hubUseCase{
-		client:        c,
-		self:          u,
-		topics:        t,
-		subscriptions: s,
+		client:        params.Client,
+		config:        params.Config,
+		queue:         params.Queue,
+		subscriptions: params.Subscriptions,
+		topics:        params.Topics,
+		updates:       params.Updates,
+		limiter:       newLimiter(params.Config.DeliveryWorkersPerHost),
+		publisher:     params.Publisher,
+		deliveries:    params.Deliveries,
+		versions:      params.Versions,
+		logger:        params.Logger,
+		polls:         make(chan struct{}, pollWorkers),
+		renewals:      make(chan struct{}, renewWorkers),
 	}
 }
 
@@ -55,12 +143,12 @@ func (ucase *hubUseCase) Verify(ctx context.Context, s domain.Subscription, mode
 	challenge.AddQuery(q)
 
 	if mode == domain.ModeSubscribe {
-		q.Add(common.HubLeaseSeconds, strconv.FormatFloat(s.LeaseSeconds(), 'g', 0, 64))
+		q.Add(common.HubLeaseSeconds, strconv.FormatFloat(s.LeaseSeconds(), 'f', 0, 64))
 	}
 
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return false, fmt.Errorf("cannot build verification request: %w", err)
 	}
@@ -69,6 +157,7 @@ func (ucase *hubUseCase) Verify(ctx context.Context, s domain.Subscription, mode
 	if err != nil {
 		return false, fmt.Errorf("cannot send verification request: %w", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
 		return false, hub.ErrNotFound
@@ -90,89 +179,1001 @@ func (ucase *hubUseCase) Verify(ctx context.Context, s domain.Subscription, mode
 	return true, nil
 }
 
+func (ucase *hubUseCase) Deny(ctx context.Context, callback *url.URL, params domain.QueryAdder) error {
+	u, _ := url.Parse(callback.String())
+	q := u.Query()
+
+	params.AddQuery(q)
+
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("cannot build denial request: %w", err)
+	}
+
+	// NOTE(toby3d): subscriber is not required to reply anything specific
+	// to the denial notification, so only delivery errors are reported.
+	resp, err := ucase.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send denial request: %w", err)
+	}
+
+	return resp.Body.Close()
+}
+
+func (ucase *hubUseCase) Revoke(ctx context.Context, suid domain.SUID, reason error) (bool, error) {
+	s, err := ucase.subscriptions.Get(ctx, suid)
+	if err != nil {
+		if errors.Is(err, subscription.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot find revoking subscription: %w", err)
+	}
+
+	if err = ucase.remove(ctx, suid); err != nil {
+		return false, fmt.Errorf("cannot revoke subscription: %w", err)
+	}
+
+	if err = ucase.Deny(ctx, s.Callback, denial{topic: s.Topic, reason: reason}); err != nil {
+		return true, fmt.Errorf("%w: %w", hub.ErrNotify, err)
+	}
+
+	return true, nil
+}
+
+func (ucase *hubUseCase) Retire(ctx context.Context, u *url.URL, reason error) (bool, error) {
+	t, err := ucase.topics.Get(ctx, u)
+	if err != nil {
+		if errors.Is(err, topic.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot find retiring topic: %w", err)
+	}
+
+	subscriptions, err := ucase.subscriptions.Fetch(ctx, t)
+	if err != nil {
+		return false, fmt.Errorf("cannot fetch retiring topic subscriptions: %w", err)
+	}
+
+	for i := range subscriptions {
+		// NOTE(toby3d): subscribers which cannot be notified will be
+		// removed anyway.
+		if _, err = ucase.Revoke(ctx, subscriptions[i].SUID(), reason); err != nil &&
+			!errors.Is(err, hub.ErrNotify) {
+			return false, fmt.Errorf("cannot retire topic: %w", err)
+		}
+	}
+
+	if _, err = ucase.topics.Delete(ctx, u); err != nil {
+		return false, fmt.Errorf("cannot purge retired topic: %w", err)
+	}
+
+	if ucase.versions != nil {
+		if _, err = ucase.versions.Delete(ctx, u); err != nil {
+			return false, fmt.Errorf("cannot purge retired topic versions: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+func (ucase *hubUseCase) Replay(ctx context.Context, u, callback *url.URL, version uint64) (int, error) {
+	now := time.Now().UTC().Round(time.Second)
+
+	t, err := ucase.topics.Get(ctx, u)
+	if err != nil {
+		return 0, fmt.Errorf("cannot find replaying topic: %w", err)
+	}
+
+	if version == 0 {
+		version = t.VersionID
+	}
+
+	content, err := ucase.rewind(ctx, *t, version)
+	if err != nil {
+		return 0, fmt.Errorf("cannot find replaying topic version: %w", err)
+	}
+
+	var subscriptions []domain.Subscription
+
+	if callback != nil {
+		s, err := ucase.subscriptions.Get(ctx, domain.NewSSID(*t, callback))
+		if err != nil {
+			return 0, fmt.Errorf("cannot find replaying subscription: %w", err)
+		}
+
+		subscriptions = []domain.Subscription{*s}
+	} else if subscriptions, err = ucase.subscriptions.Fetch(ctx, t); err != nil {
+		return 0, fmt.Errorf("cannot fetch replaying topic subscriptions: %w", err)
+	}
+
+	var count int
+
+	for i := range subscriptions {
+		if subscriptions[i].Expired(now) {
+			continue
+		}
+
+		if err = ucase.replay(ctx, subscriptions[i], content.Version(), now); err != nil {
+			return count, fmt.Errorf("cannot enqueue replay: %w", err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
 func (ucase *hubUseCase) ListenAndServe(ctx context.Context) error {
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
-	for ts := range ticker.C {
-		ts = ts.Round(time.Second)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		topics, err := ucase.topics.Fetch(ctx)
-		if err != nil {
-			return fmt.Errorf("cannot fetch topics: %w", err)
+	jobs := make(chan domain.Job)
+
+	workers := ucase.config.DeliveryWorkers
+	if workers == 0 {
+		workers = 1
+	}
+
+	for i := uint(0); i < workers; i++ {
+		go ucase.work(ctx, jobs)
+	}
+
+	for {
+		var ts time.Time
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t := <-ucase.updates:
+			ts = time.Now().UTC().Round(time.Second)
+
+			if err := ucase.distribute(ctx, t, ts); err != nil {
+				return fmt.Errorf("cannot schedule published topic deliveries: %w", err)
+			}
+		case ts = <-ticker.C:
+			ts = ts.UTC().Round(time.Second)
+
+			if err := ucase.schedule(ctx, ts); err != nil {
+				return fmt.Errorf("cannot schedule deliveries: %w", err)
+			}
+
+			if err := ucase.poll(ctx, ts); err != nil {
+				return fmt.Errorf("cannot poll topics: %w", err)
+			}
+
+			if err := ucase.renew(ctx, ts); err != nil {
+				return fmt.Errorf("cannot renew expiring subscriptions: %w", err)
+			}
+
+			if err := ucase.prune(ctx, ts); err != nil {
+				return fmt.Errorf("cannot prune deliveries history: %w", err)
+			}
+		}
+
+		if err := ucase.dispatch(ctx, jobs, ts); err != nil {
+			return fmt.Errorf("cannot dispatch queued deliveries: %w", err)
 		}
+	}
+}
+
+// dispatch hands ready jobs over to idle workers. Jobs which are already in
+// flight, exceed their callback host limit or do not find an idle worker stay
+// in queue until the next dispatch.
+func (ucase *hubUseCase) dispatch(ctx context.Context, jobs chan<- domain.Job, ts time.Time) error {
+	ready, err := ucase.queue.Fetch(ctx, ts)
+	if err != nil {
+		return fmt.Errorf("cannot fetch queued deliveries: %w", err)
+	}
+
+	for i := range ready {
+		if !ucase.limiter.Acquire(ready[i], ts) {
+			continue
+		}
+
+		select {
+		case jobs <- ready[i]:
+		default:
+			ucase.limiter.Release(ready[i])
+
+			return nil
+		}
+	}
+
+	return nil
+}
 
-		for i := range topics {
-			subscriptions, err := ucase.subscriptions.Fetch(ctx, &topics[i])
-			if err != nil {
-				return fmt.Errorf("cannot fetch subscriptions: %w", err)
+// work delivers dispatched jobs until ctx is done. Failed job is only
+// reported: it stays in queue and will be dispatched again, so a single failure
+// does not stop distribution of the rest.
+func (ucase *hubUseCase) work(ctx context.Context, jobs <-chan domain.Job) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-jobs:
+			err := ucase.deliver(ctx, j, time.Now().UTC().Round(time.Second))
+			ucase.limiter.Release(j)
+
+			if err != nil && ucase.logger != nil {
+				ucase.logger.Printf("cannot process queued delivery %#v: %s", j.SUID(), err)
 			}
+		}
+	}
+}
+
+// schedule removes expired subscriptions and enqueues delivery jobs for
+// subscriptions of topics updated since the previous schedule.
+func (ucase *hubUseCase) schedule(ctx context.Context, ts time.Time) error {
+	expired, err := ucase.subscriptions.FetchExpired(ctx, ts)
+	if err != nil {
+		return fmt.Errorf("cannot fetch expired subscriptions: %w", err)
+	}
+
+	for i := range expired {
+		if err = ucase.remove(ctx, expired[i].SUID()); err != nil {
+			return fmt.Errorf("cannot remove expired subcription: %w", err)
+		}
+	}
+
+	// NOTE(toby3d): topics updated within the same second as the previous
+	// schedule are fetched twice, which is harmless as enqueue is
+	// idempotent.
+	topics, err := ucase.topics.FetchUpdated(ctx, ucase.scheduledAt)
+	if err != nil {
+		return fmt.Errorf("cannot fetch updated topics: %w", err)
+	}
+
+	for i := range topics {
+		if err = ucase.distribute(ctx, topics[i], ts); err != nil {
+			return err
+		}
+	}
+
+	ucase.scheduledAt = ts
+
+	return nil
+}
+
+// poll refreshes topics which polling is scheduled at ts, because their
+// publishers may never notify the hub about updates. Detected changes are
+// distributed as published ones.
+func (ucase *hubUseCase) poll(ctx context.Context, ts time.Time) error {
+	if ucase.publisher == nil || ucase.config.PollInterval == 0 {
+		return nil
+	}
+
+	topics, err := ucase.topics.FetchScheduled(ctx, ts)
+	if err != nil {
+		return fmt.Errorf("cannot fetch scheduled topics: %w", err)
+	}
+
+	for i := range topics {
+		select {
+		case ucase.polls <- struct{}{}:
+		default:
+			// NOTE(toby3d): all workers are busy, the rest of topics
+			// will be polled on the next tick.
+			return nil
+		}
+
+		// NOTE(toby3d): postpone the next poll, so topic will not be
+		// polled twice while it's fetching. Publish sets the actual
+		// schedule.
+		if err = ucase.topics.Reschedule(ctx, topics[i].Self, ts.Add(ucase.config.PollInterval)); err != nil {
+			<-ucase.polls
+
+			if errors.Is(err, topic.ErrNotExist) {
+				continue
+			}
+
+			return fmt.Errorf("cannot postpone polling topic: %w", err)
+		}
+
+		go func(u *url.URL) {
+			defer func() { <-ucase.polls }()
+
+			if _, err := ucase.publisher.Publish(ctx, u); errors.Is(err, topic.ErrGone) {
+				_, _ = ucase.Retire(ctx, u, domain.ErrReasonRetired)
+			}
+		}(topics[i].Self)
+	}
 
-			for j := range subscriptions {
-				if subscriptions[j].Expired(ts) {
-					_, err = ucase.subscriptions.Delete(ctx, subscriptions[j].SUID())
-					if err != nil {
-						return fmt.Errorf("cannot remove expired subcription: %w", err)
-					}
+	return nil
+}
 
-					continue
-				}
+// renew re-verifies subscribers which leases expire within the renewal window
+// with a new challenge. Subscribers which echo it keep their subscriptions with
+// the same lease, the rest expire as usual. Each lease is re-verified once.
+func (ucase *hubUseCase) renew(ctx context.Context, ts time.Time) error {
+	window := ucase.config.RenewBefore
+	if window <= 0 {
+		return nil
+	}
 
-				if subscriptions[j].Synced(topics[i]) {
-					continue
-				}
+	expiring, err := ucase.subscriptions.FetchExpired(ctx, ts.Add(window))
+	if err != nil {
+		return fmt.Errorf("cannot fetch expiring subscriptions: %w", err)
+	}
+
+	for i := range expiring {
+		if expiring[i].Expired(ts) || !expiring[i].ReverifiedAt.Before(expiring[i].ExpiredAt.Add(-window)) {
+			continue
+		}
 
-				go ucase.push(ctx, subscriptions[j], topics[i], ts)
+		select {
+		case ucase.renewals <- struct{}{}:
+		default:
+			// NOTE(toby3d): all workers are busy, the rest of
+			// subscriptions will be renewed on the next tick.
+			return nil
+		}
+
+		if err = ucase.subscriptions.Update(ctx, expiring[i].SUID(), func(tx *domain.Subscription) (
+			*domain.Subscription, error,
+		) {
+			tx.ReverifiedAt = ts
+
+			return tx, nil
+		}); err != nil {
+			<-ucase.renewals
+
+			if errors.Is(err, subscription.ErrNotExist) {
+				continue
 			}
+
+			return fmt.Errorf("cannot mark re-verified subscription: %w", err)
 		}
+
+		go func(s domain.Subscription) {
+			defer func() { <-ucase.renewals }()
+
+			_ = ucase.reverify(ctx, s, ts)
+		}(expiring[i])
 	}
 
 	return nil
 }
 
-func (ucase *hubUseCase) push(ctx context.Context, s domain.Subscription, t domain.Topic, ts time.Time) (bool, error) {
-	req, err := http.NewRequest(http.MethodPost, s.Callback.String(), bytes.NewReader(t.Content))
+// reverify sends a subscribe verification of intent for subscription lease
+// renewed at ts and extends it if subscriber confirms it.
+func (ucase *hubUseCase) reverify(ctx context.Context, s domain.Subscription, ts time.Time) error {
+	lease := time.Duration(s.LeaseSeconds()) * time.Second
+	s.UpdatedAt = ts
+	s.ExpiredAt = ts.Add(lease)
+
+	vctx := ctx
+	if ucase.config.VerifyTimeout > 0 {
+		var cancel context.CancelFunc
+		vctx, cancel = context.WithTimeout(ctx, ucase.config.VerifyTimeout)
+
+		defer cancel()
+	}
+
+	if _, err := ucase.Verify(vctx, s, domain.ModeSubscribe); err != nil {
+		return fmt.Errorf("cannot re-verify subscription: %w", err)
+	}
+
+	if err := ucase.subscriptions.Update(ctx, s.SUID(), func(tx *domain.Subscription) (*domain.Subscription,
+		error,
+	) {
+		tx.UpdatedAt = s.UpdatedAt
+		tx.ExpiredAt = s.ExpiredAt
+		tx.RenewedAt = ts
+
+		return tx, nil
+	}); err != nil {
+		return fmt.Errorf("cannot renew subscription: %w", err)
+	}
+
+	return nil
+}
+
+// distribute enqueues delivery jobs for topic subscriptions which are not
+// synced with it.
+func (ucase *hubUseCase) distribute(ctx context.Context, t domain.Topic, ts time.Time) error {
+	subscriptions, err := ucase.subscriptions.FetchUnsynced(ctx, t)
 	if err != nil {
-		return false, fmt.Errorf("cannot build request: %w", err)
+		return fmt.Errorf("cannot fetch unsynced subscriptions: %w", err)
+	}
+
+	for i := range subscriptions {
+		if subscriptions[i].Expired(ts) {
+			continue
+		}
+
+		if err = ucase.enqueue(ctx, subscriptions[i], t, ts); err != nil {
+			return fmt.Errorf("cannot enqueue delivery: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// enqueue creates a new delivery job for subscription or resets the existing
+// one if topic was updated after it was scheduled.
+func (ucase *hubUseCase) enqueue(ctx context.Context, s domain.Subscription, t domain.Topic, ts time.Time) error {
+	err := ucase.queue.Create(ctx, s.SUID(), *domain.NewJob(s, t, ts))
+	if err == nil || !errors.Is(err, queue.ErrExist) {
+		return err
+	}
+
+	return ucase.queue.Update(ctx, s.SUID(), func(tx *domain.Job) (*domain.Job, error) {
+		// NOTE(toby3d): pending replay is delivered first, then
+		// subscriber catches up with the newer content.
+		if !tx.Outdated(t) || (tx.Replay && tx.State == domain.JobStatePending) {
+			return tx, nil
+		}
+
+		// NOTE(toby3d): new content supersedes failed one, so even dead
+		// job gets a new chance with a fresh attempts budget.
+		tx.UpdatedAt = ts
+		tx.Version = t.UpdatedAt
+		tx.VersionID = t.VersionID
+		tx.Replay = false
+		tx.State = domain.JobStatePending
+		tx.Attempts = 0
+
+		if tx.ScheduledAt.After(ts) {
+			tx.ScheduledAt = ts
+		}
+
+		return tx, nil
+	})
+}
+
+// replay creates a delivery job of topic version v for subscription, which
+// replaces the existing one.
+func (ucase *hubUseCase) replay(ctx context.Context, s domain.Subscription, v domain.TopicVersion,
+	ts time.Time,
+) error {
+	j := domain.NewReplayJob(s, v, ts)
+
+	err := ucase.queue.Create(ctx, s.SUID(), *j)
+	if err == nil || !errors.Is(err, queue.ErrExist) {
+		return err
+	}
+
+	return ucase.queue.Update(ctx, s.SUID(), func(tx *domain.Job) (*domain.Job, error) {
+		tx.UpdatedAt = ts
+		tx.Version = j.Version
+		tx.VersionID = j.VersionID
+		tx.Replay = true
+		tx.State = domain.JobStatePending
+		tx.Attempts = 0
+
+		if tx.ScheduledAt.After(ts) {
+			tx.ScheduledAt = ts
+		}
+
+		return tx, nil
+	})
+}
+
+// dequeue deletes delivered job, unless it was replaced by another content
+// version or replay while it was in flight.
+func (ucase *hubUseCase) dequeue(ctx context.Context, j domain.Job) error {
+	current, err := ucase.queue.Get(ctx, j.SUID())
+	if err != nil {
+		if errors.Is(err, queue.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	if current.VersionID != j.VersionID || current.Replay != j.Replay {
+		return nil
+	}
+
+	_, err = ucase.queue.Delete(ctx, j.SUID())
+
+	return err
+}
+
+// rewind returns topic with the content of kept version id.
+func (ucase *hubUseCase) rewind(ctx context.Context, t domain.Topic, id uint64) (domain.Topic, error) {
+	if id == t.VersionID {
+		return t, nil
+	}
+
+	if ucase.versions == nil {
+		return t, topic.ErrVersionNotExist
+	}
+
+	v, err := ucase.versions.Get(ctx, t.Self, id)
+	if err != nil {
+		return t, fmt.Errorf("cannot get replaying topic version: %w", err)
+	}
+
+	return t.Rewind(*v), nil
+}
+
+// catchUp enqueues delivery of the current topic content to subscription
+// which is still not synced with it after replay.
+func (ucase *hubUseCase) catchUp(ctx context.Context, suid domain.SUID, t domain.Topic, ts time.Time) error {
+	s, err := ucase.subscriptions.Get(ctx, suid)
+	if err != nil {
+		if errors.Is(err, subscription.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("cannot get replayed subscription: %w", err)
+	}
+
+	if s.Synced(t) || s.Expired(ts) {
+		return nil
+	}
+
+	return ucase.enqueue(ctx, *s, t, ts)
+}
+
+// deliver performs a single delivery attempt of the queued job and reschedules
+// it with backoff on failure.
+func (ucase *hubUseCase) deliver(ctx context.Context, j domain.Job, ts time.Time) error {
+	s, err := ucase.subscriptions.Get(ctx, j.SUID())
+	if err != nil {
+		if errors.Is(err, subscription.ErrNotExist) {
+			_, err = ucase.queue.Delete(ctx, j.SUID())
+
+			return err
+		}
+
+		return fmt.Errorf("cannot get delivery subscription: %w", err)
+	}
+
+	// NOTE(toby3d): pauses of callback hosts are not persisted, so they
+	// are restored from subscriptions after the hub restarts.
+	if s.Paused(ts) {
+		ucase.limiter.Pause(j.SUID().Callback().Host, s.PausedUntil)
+
+		return ucase.queue.Update(ctx, j.SUID(), func(tx *domain.Job) (*domain.Job, error) {
+			tx.ScheduledAt = s.PausedUntil
+
+			return tx, nil
+		})
+	}
+
+	if s.Suspended() {
+		switch {
+		case ucase.config.SuspendTimeout > 0 && !ts.Before(s.SuspendedAt.Add(ucase.config.SuspendTimeout)):
+			// NOTE(toby3d): callback did not recover in time, so
+			// subscriber must subscribe again when it's alive.
+			if _, err = ucase.Revoke(ctx, j.SUID(), domain.ErrReasonFailed); err != nil &&
+				!errors.Is(err, hub.ErrNotify) {
+				return fmt.Errorf("cannot remove suspended subscription: %w", err)
+			}
+
+			return nil
+		case s.ProbeAt.After(ts):
+			return ucase.queue.Update(ctx, j.SUID(), func(tx *domain.Job) (*domain.Job, error) {
+				tx.ScheduledAt = s.ProbeAt
+
+				return tx, nil
+			})
+		}
+
+		// NOTE(toby3d): this delivery is a probe of suspended
+		// callback.
+		if err = ucase.subscriptions.Update(ctx, j.SUID(), func(tx *domain.Subscription) (*domain.Subscription,
+			error,
+		) {
+			tx.Circuit = domain.CircuitStateHalfOpen
+
+			return tx, nil
+		}); err != nil {
+			return fmt.Errorf("cannot probe suspended subscription: %w", err)
+		}
+	}
+
+	t, err := ucase.topics.Get(ctx, j.Topic)
+	if err != nil {
+		if errors.Is(err, topic.ErrNotExist) {
+			_, err = ucase.queue.Delete(ctx, j.SUID())
+
+			return err
+		}
+
+		return fmt.Errorf("cannot get delivery topic: %w", err)
+	}
+
+	content, target := *t, *s
+	if j.Replay {
+		// NOTE(toby3d): subscriber asked for content which it may
+		// already have, so there is nothing to diff with.
+		target.Full = true
+
+		if content, err = ucase.rewind(ctx, *t, j.VersionID); err != nil {
+			if !errors.Is(err, topic.ErrVersionNotExist) {
+				return err
+			}
+
+			// NOTE(toby3d): version was pruned after replay was
+			// requested.
+			if _, err = ucase.queue.Delete(ctx, j.SUID()); err != nil {
+				return fmt.Errorf("cannot dequeue pruned replay: %w", err)
+			}
+
+			return ucase.catchUp(ctx, j.SUID(), *t, ts)
+		}
+	}
+
+	resp, err := ucase.push(ctx, target, content)
+	if resp.sent {
+		ucase.record(ctx, j, content, resp, err, ts)
+	}
+
+	if err == nil {
+		if err = ucase.dequeue(ctx, j); err != nil {
+			return fmt.Errorf("cannot dequeue delivered job: %w", err)
+		}
+
+		// NOTE(toby3d): replay may replace queued delivery of the newer
+		// content.
+		if j.Replay {
+			if err = ucase.catchUp(ctx, j.SUID(), *t, ts); err != nil {
+				return err
+			}
+		}
+
+		if s.Failures > 0 || s.Suspended() {
+			return ucase.resume(ctx, j.SUID())
+		}
+
+		return nil
+	}
+
+	if resp.retry > 0 {
+		return ucase.pause(ctx, j, resp.status, err, ts, resp.retry)
+	}
+
+	reason := err
+	if s, err = ucase.fail(ctx, j.SUID(), ts); err != nil {
+		if errors.Is(err, subscription.ErrNotExist) {
+			_, err = ucase.queue.Delete(ctx, j.SUID())
+
+			return err
+		}
+
+		return err
+	}
+
+	return ucase.queue.Update(ctx, j.SUID(), func(tx *domain.Job) (*domain.Job, error) {
+		tx.UpdatedAt = ts
+		tx.Attempts++
+		tx.Status = resp.status
+		tx.Error = reason.Error()
+
+		// NOTE(toby3d): job of suspended subscription waits for the
+		// next probe instead of dying.
+		if s.Suspended() {
+			tx.ScheduledAt = s.ProbeAt
+
+			return tx, nil
+		}
+
+		if tx.Attempts >= ucase.config.DeliveryAttempts {
+			tx.State = domain.JobStateDead
+
+			return tx, nil
+		}
+
+		tx.ScheduledAt = ts.Add(ucase.backoff(tx.Attempts))
+
+		return tx, nil
+	})
+}
+
+// record logs delivery attempt of job with topic content into deliveries
+// history, if any.
+func (ucase *hubUseCase) record(ctx context.Context, j domain.Job, t domain.Topic, resp response, err error,
+	ts time.Time,
+) {
+	if ucase.deliveries == nil {
+		return
+	}
+
+	d := domain.Delivery{
+		CreatedAt: ts,
+		Version:   t.UpdatedAt,
+		VersionID: t.VersionID,
+		Topic:     j.Topic,
+		Callback:  j.Callback,
+		Body:      resp.body,
+		Latency:   resp.latency,
+		Attempt:   j.Attempts + 1,
+		Status:    resp.status,
+	}
+
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	// NOTE(toby3d): history is a best effort log, it must not break
+	// content distribution.
+	_ = ucase.deliveries.Create(ctx, d)
+}
+
+// prune deletes deliveries history older than retention period, once per
+// pruneInterval.
+func (ucase *hubUseCase) prune(ctx context.Context, ts time.Time) error {
+	if ucase.deliveries == nil || ucase.config.DeliveryHistory <= 0 || ts.Sub(ucase.prunedAt) < pruneInterval {
+		return nil
+	}
+
+	if _, err := ucase.deliveries.Prune(ctx, ts.Add(-ucase.config.DeliveryHistory)); err != nil {
+		return err
+	}
+
+	ucase.prunedAt = ts
+
+	return nil
+}
+
+// fail counts a failed delivery to subscription and opens it's circuit after
+// the failure budget or a failed probe, so the next delivery is only a probe
+// after the probe interval. It returns the updated subscription.
+func (ucase *hubUseCase) fail(ctx context.Context, suid domain.SUID, ts time.Time) (*domain.Subscription, error) {
+	var out *domain.Subscription
+
+	if err := ucase.subscriptions.Update(ctx, suid, func(tx *domain.Subscription) (*domain.Subscription, error) {
+		tx.Failures++
+
+		if tx.Suspended() || (ucase.config.SuspendAfter > 0 && tx.Failures >= ucase.config.SuspendAfter) {
+			if !tx.Suspended() {
+				tx.SuspendedAt = ts
+			}
+
+			tx.Circuit = domain.CircuitStateOpen
+			tx.ProbeAt = ts.Add(ucase.config.ProbeInterval)
+
+			// NOTE(toby3d): subscription is removed on the next
+			// delivery after the suspend timeout.
+			deadline := tx.SuspendedAt.Add(ucase.config.SuspendTimeout)
+			if ucase.config.SuspendTimeout > 0 && tx.ProbeAt.After(deadline) {
+				tx.ProbeAt = deadline
+			}
+		}
+
+		out = tx
+
+		return tx, nil
+	}); err != nil {
+		return nil, fmt.Errorf("cannot count failed delivery: %w", err)
+	}
+
+	return out, nil
+}
+
+// resume closes circuit of subscription after successful delivery.
+func (ucase *hubUseCase) resume(ctx context.Context, suid domain.SUID) error {
+	if err := ucase.subscriptions.Update(ctx, suid, func(tx *domain.Subscription) (*domain.Subscription, error) {
+		tx.Failures = 0
+		tx.Circuit = domain.CircuitStateClosed
+		tx.SuspendedAt = time.Time{}
+		tx.ProbeAt = time.Time{}
+
+		return tx, nil
+	}); err != nil && !errors.Is(err, subscription.ErrNotExist) {
+		return fmt.Errorf("cannot resume subscription: %w", err)
+	}
+
+	return nil
+}
+
+// pause suspends deliveries to the callback host of job for the delay which
+// subscriber asked to retry after, but no longer than the maximum backoff, and
+// reschedules job to it. Such attempt does not open the circuit of subscriber,
+// because it is alive, but rate limits the hub, yet it is counted in job
+// attempts, so callback which never accepts content kills the job.
+func (ucase *hubUseCase) pause(ctx context.Context, j domain.Job, status int, reason error, ts time.Time,
+	delay time.Duration,
+) error {
+	if limit := ucase.config.DeliveryBackoffMax; limit > 0 && delay > limit {
+		delay = limit
+	}
+
+	until := ts.Add(delay)
+	ucase.limiter.Pause(j.SUID().Callback().Host, until)
+
+	if err := ucase.subscriptions.Update(ctx, j.SUID(), func(tx *domain.Subscription) (*domain.Subscription,
+		error,
+	) {
+		tx.PausedUntil = until
+
+		return tx, nil
+	}); err != nil && !errors.Is(err, subscription.ErrNotExist) {
+		return fmt.Errorf("cannot record paused subscription: %w", err)
+	}
+
+	return ucase.queue.Update(ctx, j.SUID(), func(tx *domain.Job) (*domain.Job, error) {
+		tx.UpdatedAt = ts
+		tx.Attempts++
+		tx.Status = status
+		tx.Error = reason.Error()
+
+		if tx.Attempts >= ucase.config.DeliveryAttempts {
+			tx.State = domain.JobStateDead
+
+			return tx, nil
+		}
+
+		tx.ScheduledAt = until
+
+		return tx, nil
+	})
+}
+
+// backoff returns an exponentially growing delay with random jitter before the
+// next delivery attempt.
+func (ucase *hubUseCase) backoff(attempts uint) time.Duration {
+	delay := ucase.config.DeliveryBackoff
+
+	for i := uint(1); i < attempts && delay < ucase.config.DeliveryBackoffMax; i++ {
+		delay *= 2
+	}
+
+	if delay > ucase.config.DeliveryBackoffMax {
+		delay = ucase.config.DeliveryBackoffMax
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// remove deletes subscription with it's queued delivery.
+func (ucase *hubUseCase) remove(ctx context.Context, suid domain.SUID) error {
+	if _, err := ucase.subscriptions.Delete(ctx, suid); err != nil {
+		return fmt.Errorf("cannot remove subscription: %w", err)
+	}
+
+	if _, err := ucase.queue.Delete(ctx, suid); err != nil {
+		return fmt.Errorf("cannot remove subscription delivery: %w", err)
+	}
+
+	return nil
+}
+
+// push distributes topic content to subscriber and returns it's response, if
+// any.
+func (ucase *hubUseCase) push(ctx context.Context, s domain.Subscription, t domain.Topic) (response, error) {
+	suid := s.SUID()
+
+	content, ok := payload(s, t)
+	if !ok {
+		// NOTE(toby3d): there is no new entries for subscriber, so it
+		// is already synced with topic.
+		return response{}, ucase.sync(ctx, suid, t)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Callback.String(), bytes.NewReader(content))
+	if err != nil {
+		return response{}, fmt.Errorf("cannot build request: %w", err)
 	}
 
 	req.Header.Set(common.HeaderContentType, t.ContentType)
-	req.Header.Set(common.HeaderLink, `<`+ucase.self.String()+`>; rel="hub", <`+s.Topic.String()+`>; rel="self"`)
-	setXHubSignatureHeader(req, domain.AlgorithmSHA512, s.Secret, t.Content)
+	// NOTE(toby3d): topic may be moved by a permanent redirect after this
+	// subscription was queued, so the self link points to it's actual URL.
+	req.Header.Set(common.HeaderLink, `<`+ucase.config.BaseURL.String()+`>; rel="hub", <`+t.Self.String()+
+		`>; rel="self"`)
+	setXHubSignatureHeader(req, domain.AlgorithmSHA512, s.Secret, content)
+
+	start := time.Now()
+	out := response{sent: true}
 
 	resp, err := ucase.client.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("cannot push: %w", err)
+		out.latency = time.Since(start)
+
+		return out, fmt.Errorf("cannot push: %w", err)
 	}
+	defer resp.Body.Close()
 
-	suid := s.SUID()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxDeliveryBody))
+	out.latency = time.Since(start)
+	out.body = strings.ToValidUTF8(string(body), "")
+	out.status = resp.StatusCode
 
 	// The subscriber's callback URL MAY return an HTTP 410 code to indicate
 	// that the subscription has been deleted, and the hub MAY terminate the
 	// subscription if it receives that code as a response.
 	if resp.StatusCode == http.StatusGone {
-		if _, err = ucase.subscriptions.Delete(ctx, suid); err != nil {
-			return false, fmt.Errorf("cannot remove deleted subscription: %w", err)
+		if err = ucase.remove(ctx, suid); err != nil {
+			return out, fmt.Errorf("cannot remove deleted subscription: %w", err)
 		}
 
-		return true, nil
+		return out, nil
 	}
 
 	// The subscriber's callback URL MUST return an HTTP 2xx response code
 	// to indicate a success.
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-		return false, hub.ErrStatus
+		// NOTE(toby3d): rate limited or temporary unavailable
+		// subscriber may ask to retry after a delay or a date.
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			out.retry, _ = httputil.RetryAfter(resp.Header.Get(common.HeaderRetryAfter), time.Now().UTC())
+		}
+
+		return out, fmt.Errorf("%w: %d", hub.ErrStatus, resp.StatusCode)
 	}
 
-	if err = ucase.subscriptions.Update(ctx, suid, func(tx *domain.Subscription) (*domain.Subscription, error) {
+	return out, ucase.sync(ctx, suid, t)
+}
+
+// sync marks subscription as synced with topic content version.
+func (ucase *hubUseCase) sync(ctx context.Context, suid domain.SUID, t domain.Topic) error {
+	if err := ucase.subscriptions.Update(ctx, suid, func(tx *domain.Subscription) (*domain.Subscription, error) {
+		// NOTE(toby3d): slow delivery of older content must not rewind
+		// subscription behind newer one.
+		if tx.SyncedVersionID > t.VersionID {
+			return tx, nil
+		}
+
 		tx.SyncedAt = t.UpdatedAt
+		tx.SyncedVersionID = t.VersionID
 
 		return tx, nil
 	}); err != nil {
-		return false, fmt.Errorf("cannot sync sybsciption status: %w", err)
+		return fmt.Errorf("cannot sync sybsciption status: %w", err)
 	}
 
-	return true, nil
+	return nil
+}
+
+// payload returns topic content which must be distributed to subscriber. For
+// feed topics it contains only entries which are added or changed since the
+// content version of the last subscription sync, unless subscriber requests
+// the full content. It returns false if there is nothing to distribute.
+func payload(s domain.Subscription, t domain.Topic) ([]byte, bool) {
+	if s.Full || len(t.Entries) == 0 {
+		return t.Content, true
+	}
+
+	updated := make(map[string]struct{})
+
+	for i := range t.Entries {
+		if t.Entries[i].VersionID > s.SyncedVersionID {
+			updated[t.Entries[i].ID] = struct{}{}
+		}
+	}
+
+	switch len(updated) {
+	case 0:
+		return nil, false
+	case len(t.Entries):
+		return t.Content, true
+	}
+
+	f, err := feed.Parse(t.ContentType, t.Content)
+	if err != nil {
+		return t.Content, true
+	}
+
+	content, err := f.Filter(func(id string) bool {
+		_, ok := updated[id]
+
+		return ok
+	})
+	if err != nil {
+		return t.Content, true
+	}
+
+	return content, true
+}
+
+func (d denial) AddQuery(q url.Values) {
+	domain.ModeDenied.AddQuery(q)
+	q.Add(common.HubTopic, d.topic.String())
+	q.Add(common.HubReason, domain.Reason(d.reason).Error())
 }
 
 func setXHubSignatureHeader(req *http.Request, alg domain.Algorithm, secret domain.Secret, body []byte) {
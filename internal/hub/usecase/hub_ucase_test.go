@@ -2,17 +2,26 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/common"
 	"source.toby3d.me/toby3d/hub/internal/domain"
 	hubucase "source.toby3d.me/toby3d/hub/internal/hub/usecase"
+	queuememoryrepo "source.toby3d.me/toby3d/hub/internal/queue/repository/memory"
 	subscriptionmemoryrepo "source.toby3d.me/toby3d/hub/internal/subscription/repository/memory"
+	topicpkg "source.toby3d.me/toby3d/hub/internal/topic"
 	topicmemoryrepo "source.toby3d.me/toby3d/hub/internal/topic/repository/memory"
+	topicucase "source.toby3d.me/toby3d/hub/internal/topic/usecase"
 )
 
 func TestHubUseCase_Verify(t *testing.T) {
@@ -28,10 +37,12 @@ func TestHubUseCase_Verify(t *testing.T) {
 	topics := topicmemoryrepo.NewMemoryTopicRepository()
 	subscription := domain.TestSubscription(t, srv.URL)
 
-	ok, err := hubucase.NewHubUseCase(topics, subscriptions, srv.Client(), &url.URL{
-		Scheme: "https",
-		Host:   "hub.example.com",
-		Path:   "/",
+	ok, err := hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        domain.TestConfig(t),
 	}).Verify(context.Background(), *subscription, domain.ModeSubscribe)
 	if err != nil {
 		t.Fatal(err)
@@ -41,3 +52,742 @@ func TestHubUseCase_Verify(t *testing.T) {
 		t.Errorf("want %t, got %t", true, ok)
 	}
 }
+
+func TestHubUseCase_ListenAndServe(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	delivered := make(chan struct{})
+
+	var attempts int32
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// NOTE(toby3d): first delivery fails, so hub must retry it later.
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		close(delivered)
+	}))
+	t.Cleanup(srv.Close)
+
+	subscription := domain.TestSubscription(t, srv.URL)
+	subscription.Topic = topic.Self
+	subscription.SyncedAt = topic.UpdatedAt.Add(-1 * time.Hour)
+
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	if err := subscriptions.Create(context.Background(), subscription.SUID(), *subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := subscriptionmemoryrepo.NewMemoryDeliveryRepository()
+	config := domain.TestConfig(t)
+	config.DeliveryBackoff = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	go hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        config,
+		Deliveries:    deliveries,
+	}).ListenAndServe(ctx)
+
+	select {
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	case <-delivered:
+	}
+
+	for ctx.Err() == nil {
+		out, err := subscriptions.Get(ctx, subscription.SUID())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !out.Synced(*topic) {
+			time.Sleep(10 * time.Millisecond)
+
+			continue
+		}
+
+		history, err := deliveries.FetchBySUID(ctx, subscription.SUID())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(history) != 2 {
+			t.Fatalf("want %d logged deliveries, got %d", 2, len(history))
+		}
+
+		// NOTE(toby3d): the latest delivery goes first.
+		if history[0].Status != http.StatusNoContent || history[0].Attempt != 2 || history[0].Failed() {
+			t.Errorf("want successful second attempt, got %+v", history[0])
+		}
+
+		if history[1].Status != http.StatusServiceUnavailable || !history[1].Failed() {
+			t.Errorf("want failed first attempt, got %+v", history[1])
+		}
+
+		return
+	}
+
+	t.Error("subscription is not synced after successful delivery")
+}
+
+func TestHubUseCase_ListenAndServe_Workers(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		workers, perHost uint
+		expect           int32
+	}{
+		"workers":  {workers: 2, perHost: 0, expect: 2},
+		"per host": {workers: 4, perHost: 1, expect: 1},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			const subscribers int = 4
+
+			topic := domain.TestTopic(t)
+			delivered := make(chan struct{}, subscribers)
+
+			var inFlight, peak int32
+
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				current := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+
+				for {
+					last := atomic.LoadInt32(&peak)
+					if current <= last || atomic.CompareAndSwapInt32(&peak, last, current) {
+						break
+					}
+				}
+
+				// NOTE(toby3d): hold request, so concurrent deliveries
+				// overlap.
+				time.Sleep(50 * time.Millisecond)
+				w.WriteHeader(http.StatusNoContent)
+
+				delivered <- struct{}{}
+			}))
+			t.Cleanup(srv.Close)
+
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+			if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+				t.Fatal(err)
+			}
+
+			subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+
+			for i := 0; i < subscribers; i++ {
+				subscription := domain.TestSubscription(t, srv.URL+"/"+strconv.Itoa(i))
+				subscription.Topic = topic.Self
+				subscription.SyncedAt = topic.UpdatedAt.Add(-1 * time.Hour)
+				subscription.SyncedVersionID = 0
+
+				if err := subscriptions.Create(context.Background(), subscription.SUID(),
+					*subscription); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config := domain.TestConfig(t)
+			config.DeliveryWorkers = tc.workers
+			config.DeliveryWorkersPerHost = tc.perHost
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			go hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+				Topics:        topics,
+				Subscriptions: subscriptions,
+				Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+				Client:        srv.Client(),
+				Config:        config,
+			}).ListenAndServe(ctx)
+
+			for i := 0; i < subscribers; i++ {
+				select {
+				case <-ctx.Done():
+					t.Fatal(ctx.Err())
+				case <-delivered:
+				}
+			}
+
+			if actual := atomic.LoadInt32(&peak); actual != tc.expect {
+				t.Errorf("want %d concurrent deliveries, got %d", tc.expect, actual)
+			}
+		})
+	}
+}
+
+func TestHubUseCase_ListenAndServe_Diff(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		contentType   string
+		first, second string
+		expect        []string
+		unexpect      []string
+	}{
+		"feed": {
+			contentType: "application/atom+xml",
+			first: `<feed xmlns="http://www.w3.org/2005/Atom"><id>urn:lipsum</id>` +
+				`<entry><id>urn:lipsum:1</id><title>First</title></entry>` +
+				`<entry><id>urn:lipsum:2</id><title>Second</title></entry></feed>`,
+			second: `<feed xmlns="http://www.w3.org/2005/Atom"><id>urn:lipsum</id>` +
+				`<entry><id>urn:lipsum:3</id><title>Third</title></entry>` +
+				`<entry><id>urn:lipsum:1</id><title>First</title></entry>` +
+				`<entry><id>urn:lipsum:2</id><title>Second, edited</title></entry></feed>`,
+			expect:   []string{"urn:lipsum:3", "Second, edited"},
+			unexpect: []string{"urn:lipsum:1"},
+		},
+		"content": {
+			contentType: "text/plain",
+			first:       "hello, world",
+			second:      "hello, new world",
+			expect:      []string{"hello, new world"},
+		},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			bodies := make(chan string, 1)
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies <- string(body)
+
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(srv.Close)
+
+			topic := domain.TestTopic(t)
+			topic.ContentType = tc.contentType
+			updates := make(chan domain.Topic, 1)
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+			publisher := topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{
+				Topics:  topics,
+				Updates: updates,
+			})
+
+			// NOTE(toby3d): both versions are most likely published
+			// within the same second.
+			first := *topic
+			first.Content = []byte(tc.first)
+
+			if _, err := publisher.PublishContent(context.Background(), first); err != nil {
+				t.Fatal(err)
+			}
+
+			<-updates
+
+			subscription := domain.TestSubscription(t, srv.URL+"/")
+			subscription.Topic = topic.Self
+			subscription.SyncedVersionID = 1
+
+			subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+			if err := subscriptions.Create(context.Background(), subscription.SUID(),
+				*subscription); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			go hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+				Topics:        topics,
+				Subscriptions: subscriptions,
+				Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+				Client:        srv.Client(),
+				Config:        domain.TestConfig(t),
+				Updates:       updates,
+			}).ListenAndServe(ctx)
+
+			second := *topic
+			second.Content = []byte(tc.second)
+
+			if _, err := publisher.PublishContent(context.Background(), second); err != nil {
+				t.Fatal(err)
+			}
+
+			var body string
+
+			select {
+			case <-ctx.Done():
+				t.Fatal(ctx.Err())
+			case body = <-bodies:
+			}
+
+			for _, expect := range tc.expect {
+				if !strings.Contains(body, expect) {
+					t.Errorf("want %q in distributed content, got %q", expect, body)
+				}
+			}
+
+			for _, unexpect := range tc.unexpect {
+				if strings.Contains(body, unexpect) {
+					t.Errorf("want unchanged %q not in distributed content, got %q", unexpect, body)
+				}
+			}
+		})
+	}
+}
+
+func TestHubUseCase_ListenAndServe_Renew(t *testing.T) {
+	t.Parallel()
+
+	leases := make(chan string, 1)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue(common.HubMode) != domain.ModeSubscribe.String() {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		fmt.Fprint(w, r.FormValue(common.HubChallenge))
+
+		select {
+		case leases <- r.FormValue(common.HubLeaseSeconds):
+		default:
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	// NOTE(toby3d): subscription lease expires within the renewal window.
+	in := domain.TestSubscription(t, srv.URL)
+	in.ExpiredAt = in.UpdatedAt.Add(time.Minute)
+
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	if err := subscriptions.Create(context.Background(), in.SUID(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	config := domain.TestConfig(t)
+	config.RenewBefore = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	go hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topicmemoryrepo.NewMemoryTopicRepository(),
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        config,
+	}).ListenAndServe(ctx)
+
+	select {
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	case lease := <-leases:
+		if expect := "60"; lease != expect {
+			t.Errorf("want %s lease, got %s", expect, lease)
+		}
+	}
+
+	for ctx.Err() == nil {
+		out, err := subscriptions.Get(ctx, in.SUID())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !out.RenewedAt.IsZero() {
+			if !out.ExpiredAt.After(in.ExpiredAt) {
+				t.Errorf("want lease extended after %s, got %s", in.ExpiredAt, out.ExpiredAt)
+			}
+
+			if out.ReverifiedAt.IsZero() {
+				t.Error("want non-zero re-verification time")
+			}
+
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Error("subscription is not renewed after successful re-verification")
+}
+
+func TestHubUseCase_ListenAndServe_RetryAfter(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	delivered := make(chan time.Time, 2)
+
+	var attempts int32
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- time.Now()
+
+		// NOTE(toby3d): first delivery is rate limited, so hub must
+		// wait before the next one.
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set(common.HeaderRetryAfter, "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	subscription := domain.TestSubscription(t, srv.URL)
+	subscription.Topic = topic.Self
+	subscription.SyncedAt = topic.UpdatedAt.Add(-1 * time.Hour)
+
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	if err := subscriptions.Create(context.Background(), subscription.SUID(), *subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	config := domain.TestConfig(t)
+	config.DeliveryBackoff = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	go hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        config,
+	}).ListenAndServe(ctx)
+
+	var first, second time.Time
+
+	for _, dst := range []*time.Time{&first, &second} {
+		select {
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		case *dst = <-delivered:
+		}
+	}
+
+	if delay := second.Sub(first); delay < time.Second {
+		t.Errorf("want delivery retried after %s, got %s", 2*time.Second, delay)
+	}
+
+	out, err := subscriptions.Get(ctx, subscription.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.PausedUntil.IsZero() {
+		t.Error("want recorded pause of subscription")
+	}
+}
+
+func TestHubUseCase_ListenAndServe_RateLimited(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+
+	var attempts int32
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+
+		// NOTE(toby3d): callback never accepts content.
+		w.Header().Set(common.HeaderRetryAfter, "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+
+	subscription := domain.TestSubscription(t, srv.URL)
+	subscription.Topic = topic.Self
+	subscription.SyncedAt = topic.UpdatedAt.Add(-1 * time.Hour)
+
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	if err := subscriptions.Create(context.Background(), subscription.SUID(), *subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	config := domain.TestConfig(t)
+	config.DeliveryAttempts = 3
+	config.DeliveryBackoff = time.Millisecond
+	// NOTE(toby3d): Retry-After delay is clamped by the maximum backoff.
+	config.DeliveryBackoffMax = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	queue := queuememoryrepo.NewMemoryQueueRepository()
+
+	go hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queue,
+		Client:        srv.Client(),
+		Config:        config,
+	}).ListenAndServe(ctx)
+
+	for ctx.Err() == nil {
+		j, err := queue.Get(ctx, subscription.SUID())
+		if err == nil && j.State == domain.JobStateDead {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := ctx.Err(); err != nil {
+		t.Fatal("rate limited delivery is retried forever")
+	}
+
+	// NOTE(toby3d): dead job is never retried.
+	time.Sleep(100 * time.Millisecond)
+
+	if actual := atomic.LoadInt32(&attempts); actual != int32(config.DeliveryAttempts) {
+		t.Errorf("want %d deliveries, got %d", config.DeliveryAttempts, actual)
+	}
+}
+
+func TestHubUseCase_ListenAndServe_Suspend(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		alive bool
+	}{
+		"resumed": {alive: true},
+		"removed": {alive: false},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			topic := domain.TestTopic(t)
+			denied := make(chan string, 1)
+
+			var attempts int32
+
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					denied <- r.FormValue(common.HubReason)
+
+					return
+				}
+
+				// NOTE(toby3d): callback fails until the probe.
+				if atomic.AddInt32(&attempts, 1) <= 2 || !tc.alive {
+					w.WriteHeader(http.StatusInternalServerError)
+
+					return
+				}
+
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(srv.Close)
+
+			in := domain.TestSubscription(t, srv.URL)
+			in.Topic = topic.Self
+			in.SyncedAt = topic.UpdatedAt.Add(-1 * time.Hour)
+
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+			if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+				t.Fatal(err)
+			}
+
+			subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+			if err := subscriptions.Create(context.Background(), in.SUID(), *in); err != nil {
+				t.Fatal(err)
+			}
+
+			config := domain.TestConfig(t)
+			config.DeliveryBackoff = time.Millisecond
+			config.SuspendAfter = 2
+			config.ProbeInterval = 2 * time.Second
+			config.SuspendTimeout = time.Hour
+
+			if !tc.alive {
+				config.ProbeInterval = time.Hour
+				config.SuspendTimeout = 2 * time.Second
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			t.Cleanup(cancel)
+
+			go hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+				Topics:        topics,
+				Subscriptions: subscriptions,
+				Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+				Client:        srv.Client(),
+				Config:        config,
+			}).ListenAndServe(ctx)
+
+			for ctx.Err() == nil {
+				out, err := subscriptions.Get(ctx, in.SUID())
+				if err == nil && out.Suspended() {
+					break
+				}
+
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			if !tc.alive {
+				select {
+				case <-ctx.Done():
+					t.Fatal(ctx.Err())
+				case reason := <-denied:
+					if reason != domain.ErrReasonFailed.Error() {
+						t.Errorf("want '%s' reason, got '%s'", domain.ErrReasonFailed, reason)
+					}
+				}
+
+				if actual := atomic.LoadInt32(&attempts); actual != 2 {
+					t.Errorf("want %d deliveries to suspended callback, got %d", 2, actual)
+				}
+
+				return
+			}
+
+			for ctx.Err() == nil {
+				out, err := subscriptions.Get(ctx, in.SUID())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if out.Circuit == domain.CircuitStateClosed && out.Failures == 0 && out.Synced(*topic) {
+					if actual := atomic.LoadInt32(&attempts); actual != 3 {
+						t.Errorf("want %d deliveries, got %d", 3, actual)
+					}
+
+					return
+				}
+
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			t.Error("subscription is not resumed after successful probe")
+		})
+	}
+}
+
+func TestHubUseCase_Replay(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	topic.VersionID = 2
+
+	bodies := make(chan string, 2)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	// NOTE(toby3d): subscriptions are stored by canonical URLs.
+	subscription := domain.TestSubscription(t, srv.URL+"/")
+	subscription.Topic = topic.Self
+	subscription.SyncedAt = topic.UpdatedAt
+	subscription.SyncedVersionID = topic.VersionID
+
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	versions := topicmemoryrepo.NewMemoryVersionRepository()
+	old := topic.Version()
+	old.ID = 1
+	old.Content = []byte("hello, old world")
+
+	for _, v := range []domain.TopicVersion{old, topic.Version()} {
+		if err := versions.Create(context.Background(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	if err := subscriptions.Create(context.Background(), subscription.SUID(), *subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+
+	ucase := hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         queuememoryrepo.NewMemoryQueueRepository(),
+		Client:        srv.Client(),
+		Config:        domain.TestConfig(t),
+		Versions:      versions,
+	})
+
+	go ucase.ListenAndServe(ctx)
+
+	if _, err := ucase.Replay(ctx, topic.Self, nil, 3); !errors.Is(err, topicpkg.ErrVersionNotExist) {
+		t.Errorf("want %v error, got %v", topicpkg.ErrVersionNotExist, err)
+	}
+
+	for _, tc := range []struct {
+		callback *url.URL
+		expect   string
+		version  uint64
+	}{
+		{callback: subscription.Callback, version: old.ID, expect: string(old.Content)},
+		{expect: string(topic.Content)},
+	} {
+		count, err := ucase.Replay(ctx, topic.Self, tc.callback, tc.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 1 {
+			t.Errorf("want %d replays, got %d", 1, count)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		case body := <-bodies:
+			if body != tc.expect {
+				t.Errorf("want %q replayed content, got %q", tc.expect, body)
+			}
+		}
+	}
+
+	// NOTE(toby3d): replay of old version must not rewind subscriber.
+	out, err := subscriptions.Get(ctx, subscription.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.SyncedVersionID != topic.VersionID {
+		t.Errorf("want synced %d version, got %d", topic.VersionID, out.SyncedVersionID)
+	}
+}
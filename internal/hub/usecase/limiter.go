@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+)
+
+// limiter tracks in-flight deliveries so the same subscription is never pushed
+// twice at once and a single callback host is not flooded by concurrent
+// requests or requests after it asks to retry later.
+type limiter struct {
+	mutex    *sync.Mutex
+	inFlight map[string]struct{}
+	hosts    map[string]uint
+	paused   map[string]time.Time
+	perHost  uint
+}
+
+func newLimiter(perHost uint) *limiter {
+	return &limiter{
+		mutex:    new(sync.Mutex),
+		inFlight: make(map[string]struct{}),
+		hosts:    make(map[string]uint),
+		paused:   make(map[string]time.Time),
+		perHost:  perHost,
+	}
+}
+
+// Acquire reserves delivery slot for job at ts and reports whether it was
+// reserved.
+func (l *limiter) Acquire(j domain.Job, ts time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	suid := j.SUID()
+	id, host := suid.GoString(), suid.Callback().Host
+
+	if _, ok := l.inFlight[id]; ok {
+		return false
+	}
+
+	if until, ok := l.paused[host]; ok {
+		if until.After(ts) {
+			return false
+		}
+
+		delete(l.paused, host)
+	}
+
+	if l.perHost > 0 && l.hosts[host] >= l.perHost {
+		return false
+	}
+
+	l.inFlight[id] = struct{}{}
+	l.hosts[host]++
+
+	return true
+}
+
+// Pause suspends deliveries to canonical callback host until the given time.
+func (l *limiter) Pause(host string, until time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.paused[host].Before(until) {
+		l.paused[host] = until
+	}
+}
+
+// Release frees delivery slot reserved by Acquire.
+func (l *limiter) Release(j domain.Job) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	suid := j.SUID()
+	host := suid.Callback().Host
+
+	delete(l.inFlight, suid.GoString())
+
+	if l.hosts[host] <= 1 {
+		delete(l.hosts, host)
+
+		return
+	}
+
+	l.hosts[host]--
+}
@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Now().UTC().Round(time.Second)
+	l := newLimiter(2)
+
+	first := domain.TestJob(t)
+	if !l.Acquire(*first, ts) {
+		t.Fatal("want acquired job")
+	}
+
+	// NOTE(toby3d): the same subscription in another case is still in
+	// flight.
+	duplicate := domain.TestJob(t)
+	duplicate.Callback.Host = "EXAMPLE.NET:443"
+
+	if l.Acquire(*duplicate, ts) {
+		t.Error("want in flight job to be rejected")
+	}
+
+	second := domain.TestJob(t)
+	second.Callback.Path = "/second"
+
+	if !l.Acquire(*second, ts) {
+		t.Fatal("want acquired job")
+	}
+
+	third := domain.TestJob(t)
+	third.Callback.Path = "/third"
+
+	if l.Acquire(*third, ts) {
+		t.Error("want job over per host limit to be rejected")
+	}
+
+	// NOTE(toby3d): equivalent hosts share the same limit.
+	equivalent := domain.TestJob(t)
+	equivalent.Callback.Host = "Example.net:443"
+	equivalent.Callback.Path = "/equivalent"
+
+	if l.Acquire(*equivalent, ts) {
+		t.Error("want job over per host limit to be rejected")
+	}
+
+	other := domain.TestJob(t)
+	other.Callback.Host = "example.org"
+
+	if !l.Acquire(*other, ts) {
+		t.Error("want job of another host to be acquired")
+	}
+
+	l.Release(*first)
+
+	if !l.Acquire(*third, ts) {
+		t.Error("want job to be acquired after release")
+	}
+
+	if l.Acquire(*first, ts) {
+		t.Error("want job over per host limit to be rejected")
+	}
+}
+
+func TestLimiter_Pause(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Now().UTC().Round(time.Second)
+	l := newLimiter(0)
+	j := domain.TestJob(t)
+
+	l.Pause(j.Callback.Host, ts.Add(time.Minute))
+	// NOTE(toby3d): shorter pause does not cancel the longer one.
+	l.Pause(j.Callback.Host, ts.Add(time.Second))
+
+	if l.Acquire(*j, ts.Add(time.Second)) {
+		t.Error("want job of paused host to be rejected")
+	}
+
+	if !l.Acquire(*j, ts.Add(time.Minute)) {
+		t.Error("want job to be acquired after pause")
+	}
+}
@@ -0,0 +1,189 @@
+// Package netutil protects outbound requests of the hub from server-side request
+// forgery: topic and callback URLs are supplied by anonymous users, so they
+// must not point to loopback, private or reserved networks, except explicitly
+// allowed ones.
+package netutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Dialer resolves host, checks all of it's addresses and connects to the first
+// allowed one, so the checked address is the connected one and DNS rebinding
+// between check and connection is not possible.
+type Dialer struct {
+	dialer   *net.Dialer
+	resolver *net.Resolver
+	allow    []netip.Prefix
+}
+
+// ErrForbidden describes a connection to the blocked address.
+var ErrForbidden = errors.New("connection to this address is forbidden")
+
+// reserved contains special-purpose networks which are not covered by the
+// netip.Addr predicates.
+//
+// See: https://www.iana.org/assignments/iana-ipv4-special-registry/
+// See: https://www.iana.org/assignments/iana-ipv6-special-registry/
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// NewDialer creates a new Dialer which blocks private, loopback, link-local,
+// multicast and reserved addresses, except networks in allow.
+func NewDialer(allow ...netip.Prefix) *Dialer {
+	out := &Dialer{
+		resolver: net.DefaultResolver,
+		allow:    allow,
+	}
+
+	out.dialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// NOTE(toby3d): check the address once again right before the
+		// connection, just in case.
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("cannot parse dialing address: %w", err)
+			}
+
+			if !out.Allowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbidden, addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+
+	return out
+}
+
+// ParsePrefixes parses list of networks in CIDR notation or single addresses.
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(list))
+
+	for _, v := range list {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse network address: %w", err)
+			}
+
+			out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse network: %w", err)
+		}
+
+		out = append(out, prefix.Masked())
+	}
+
+	return out, nil
+}
+
+// Allowed reports whether connections to addr are allowed.
+func (d *Dialer) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	for i := range d.allow {
+		if d.allow[i].Contains(addr) {
+			return true
+		}
+	}
+
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for i := range reserved {
+		if reserved[i].Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DialContext connects to the address on the named network like
+// net.Dialer.DialContext, but only if the resolved address is allowed.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse dialing address: %w", err)
+	}
+
+	addrs, err := d.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %w", host, err)
+	}
+
+	errs := make([]error, 0, len(addrs))
+
+	for _, addr := range addrs {
+		if !d.Allowed(addr) {
+			errs = append(errs, fmt.Errorf("%w: %s resolved to %s", ErrForbidden, host, addr))
+
+			continue
+		}
+
+		// NOTE(toby3d): connect to the checked address instead of host
+		// name, so it cannot be resolved again into something else.
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		return conn, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("cannot resolve %s: no addresses", host)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// Transport returns a copy of http.DefaultTransport which dials through d.
+// Proxy settings of environment are ignored, because the proxy address is not
+// the address of requested resource.
+func (d *Dialer) Transport() *http.Transport {
+	out := http.DefaultTransport.(*http.Transport).Clone()
+	out.Proxy = nil
+	out.DialContext = d.DialContext
+
+	return out
+}
@@ -0,0 +1,82 @@
+package netutil_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"source.toby3d.me/toby3d/hub/internal/netutil"
+)
+
+func TestDialer_Allowed(t *testing.T) {
+	t.Parallel()
+
+	dialer := netutil.NewDialer(netip.MustParsePrefix("10.1.0.0/16"))
+
+	for input, expect := range map[string]bool{
+		"93.184.216.34":      true,
+		"2606:2800:220:1::1": true,
+		"10.1.2.3":           true,
+		"10.2.3.4":           false,
+		"127.0.0.1":          false,
+		"::1":                false,
+		"::ffff:127.0.0.1":   false,
+		"169.254.169.254":    false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+		"255.255.255.255":    false,
+		"64:ff9b::a9fe:a9fe": false,
+		"2002:7f00:1::":      false,
+		"224.0.0.1":          false,
+		"2001:db8::1":        false,
+	} {
+		input, expect := input, expect
+
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+
+			if actual := dialer.Allowed(netip.MustParseAddr(input)); actual != expect {
+				t.Errorf("want %t, got %t", expect, actual)
+			}
+		})
+	}
+}
+
+func TestDialer_DialContext(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "hello, world")
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: netutil.NewDialer().Transport()}
+
+	if _, err := client.Get(srv.URL); !errors.Is(err, netutil.ErrForbidden) {
+		t.Errorf("want %v error, got %v", netutil.ErrForbidden, err)
+	}
+
+	allow, err := netutil.ParsePrefixes([]string{"127.0.0.1", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client = &http.Client{Transport: netutil.NewDialer(allow...).Transport()}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("want %d status, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
@@ -0,0 +1,282 @@
+// Package policy restricts topics and callbacks which the hub accepts
+// subscriptions for by allow and deny lists of host and path patterns.
+package policy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+)
+
+type (
+	// Pattern matches URLs by host and path. Host matches exactly, or with
+	// all it's subdomains if pattern starts with "*.", or any host if it's
+	// "*". Path matches exactly, or as a prefix if it ends with "*", or any
+	// path if pattern has no path.
+	//
+	// For example: "example.com", "*.example.com/blog/*".
+	Pattern struct {
+		host string
+		path string
+	}
+
+	// Policy contains allow and deny lists of topics and callbacks. Deny
+	// lists take precedence, empty allow list allows everything.
+	Policy struct {
+		TopicAllow    []Pattern
+		TopicDeny     []Pattern
+		CallbackAllow []Pattern
+		CallbackDeny  []Pattern
+	}
+
+	// Engine evaluates the current policy, which can be replaced at any
+	// time without blocking evaluations.
+	Engine struct {
+		policy atomic.Pointer[Policy]
+	}
+)
+
+const (
+	kindTopic    string = "topic"
+	kindCallback string = "callback"
+
+	actionAllow string = "allow"
+	actionDeny  string = "deny"
+)
+
+var ErrSyntax = errors.New("invalid policy syntax")
+
+func ParsePattern(v string) (Pattern, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return Pattern{}, fmt.Errorf("%w: empty pattern", ErrSyntax)
+	}
+
+	// NOTE(toby3d): scheme does not matter for policy.
+	if _, rest, ok := strings.Cut(v, "://"); ok {
+		v = rest
+	}
+
+	host, path, _ := strings.Cut(v, "/")
+	if host == "" || (strings.Contains(host, "*") && host != "*" &&
+		(!strings.HasPrefix(host, "*.") || strings.Contains(host[2:], "*"))) {
+		return Pattern{}, fmt.Errorf("%w: invalid host of %s pattern", ErrSyntax, v)
+	}
+
+	out := Pattern{host: host}
+	if strings.Contains(v, "/") {
+		out.path = "/" + path
+	}
+
+	return out, nil
+}
+
+// Match reports whether u matches pattern.
+func (p Pattern) Match(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+
+	switch {
+	case p.host == "*":
+	case strings.HasPrefix(p.host, "*."):
+		if host != p.host[2:] && !strings.HasSuffix(host, p.host[1:]) {
+			return false
+		}
+	case host != p.host:
+		return false
+	}
+
+	if p.path == "" {
+		return true
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if prefix, ok := strings.CutSuffix(p.path, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+
+	return path == p.path
+}
+
+func (p Pattern) String() string {
+	return p.host + p.path
+}
+
+// New creates a new policy from lists of patterns.
+func New(topicAllow, topicDeny, callbackAllow, callbackDeny []string) (*Policy, error) {
+	out := new(Policy)
+
+	for src, dst := range map[*[]string]*[]Pattern{
+		&topicAllow:    &out.TopicAllow,
+		&topicDeny:     &out.TopicDeny,
+		&callbackAllow: &out.CallbackAllow,
+		&callbackDeny:  &out.CallbackDeny,
+	} {
+		for _, v := range *src {
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+
+			pattern, err := ParsePattern(v)
+			if err != nil {
+				return nil, err
+			}
+
+			*dst = append(*dst, pattern)
+		}
+	}
+
+	return out, nil
+}
+
+// Parse reads policy rules from r, one "<allow|deny> <topic|callback>
+// <pattern>" rule per line. Empty lines and lines starting with "#" are
+// ignored.
+func Parse(r io.Reader) (*Policy, error) {
+	out := new(Policy)
+	scanner := bufio.NewScanner(r)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%w: line %d: want 3 fields, got %d", ErrSyntax, line, len(fields))
+		}
+
+		pattern, err := ParsePattern(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		switch strings.ToLower(fields[0]) + " " + strings.ToLower(fields[1]) {
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown rule '%s %s'", ErrSyntax, line, fields[0], fields[1])
+		case actionAllow + " " + kindTopic:
+			out.TopicAllow = append(out.TopicAllow, pattern)
+		case actionDeny + " " + kindTopic:
+			out.TopicDeny = append(out.TopicDeny, pattern)
+		case actionAllow + " " + kindCallback:
+			out.CallbackAllow = append(out.CallbackAllow, pattern)
+		case actionDeny + " " + kindCallback:
+			out.CallbackDeny = append(out.CallbackDeny, pattern)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read policy: %w", err)
+	}
+
+	return out, nil
+}
+
+// Load creates a new policy from lists of config and rules of it's policy
+// file, if any.
+func Load(config *domain.Config) (*Policy, error) {
+	out, err := New(config.TopicAllow, config.TopicDeny, config.CallbackAllow, config.CallbackDeny)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse policy of config: %w", err)
+	}
+
+	if config.PolicyFile == "" {
+		return out, nil
+	}
+
+	f, err := os.Open(config.PolicyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open policy file: %w", err)
+	}
+	defer f.Close()
+
+	file, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse policy file: %w", err)
+	}
+
+	out.TopicAllow = append(out.TopicAllow, file.TopicAllow...)
+	out.TopicDeny = append(out.TopicDeny, file.TopicDeny...)
+	out.CallbackAllow = append(out.CallbackAllow, file.CallbackAllow...)
+	out.CallbackDeny = append(out.CallbackDeny, file.CallbackDeny...)
+
+	return out, nil
+}
+
+// Topic returns domain.ErrReasonPolicy error if topic u is not allowed.
+func (p *Policy) Topic(u *url.URL) error {
+	if !allowed(u, p.TopicAllow, p.TopicDeny) {
+		return fmt.Errorf("%w: topic %s is not allowed", domain.ErrReasonPolicy, u)
+	}
+
+	return nil
+}
+
+// Callback returns domain.ErrReasonCallback error if callback u is not
+// allowed.
+func (p *Policy) Callback(u *url.URL) error {
+	if !allowed(u, p.CallbackAllow, p.CallbackDeny) {
+		return fmt.Errorf("%w: %s", domain.ErrReasonCallback, u)
+	}
+
+	return nil
+}
+
+func NewEngine(policy *Policy) *Engine {
+	out := new(Engine)
+	out.Store(policy)
+
+	return out
+}
+
+// Store replaces the current policy. Nil policy allows everything.
+func (e *Engine) Store(policy *Policy) {
+	if policy == nil {
+		policy = new(Policy)
+	}
+
+	e.policy.Store(policy)
+}
+
+// Check returns error if subscription of callback to topic is not allowed by
+// the current policy.
+func (e *Engine) Check(topic, callback *url.URL) error {
+	policy := e.policy.Load()
+
+	if err := policy.Callback(callback); err != nil {
+		return err
+	}
+
+	return policy.Topic(topic)
+}
+
+func allowed(u *url.URL, allow, deny []Pattern) bool {
+	for i := range deny {
+		if deny[i].Match(u) {
+			return false
+		}
+	}
+
+	if len(allow) == 0 {
+		return true
+	}
+
+	for i := range allow {
+		if allow[i].Match(u) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,124 @@
+package policy_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/policy"
+)
+
+func TestPattern_Match(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		pattern string
+		input   string
+		expect  bool
+	}{
+		"host":          {pattern: "example.com", input: "https://example.com/feed", expect: true},
+		"other host":    {pattern: "example.com", input: "https://example.org/feed", expect: false},
+		"subdomain":     {pattern: "*.example.com", input: "https://blog.example.com/", expect: true},
+		"apex":          {pattern: "*.example.com", input: "https://example.com/", expect: true},
+		"suffix":        {pattern: "*.example.com", input: "https://notexample.com/", expect: false},
+		"any":           {pattern: "*", input: "https://example.net:8443/", expect: true},
+		"path":          {pattern: "example.com/feed", input: "https://example.com/feed", expect: true},
+		"other path":    {pattern: "example.com/feed", input: "https://example.com/feed/2", expect: false},
+		"path prefix":   {pattern: "example.com/blog/*", input: "https://example.com/blog/feed", expect: true},
+		"scheme":        {pattern: "https://example.com/blog/*", input: "http://example.com/blog/", expect: true},
+		"prefix escape": {pattern: "example.com/blog/*", input: "https://example.com/blogger", expect: false},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pattern, err := policy.ParsePattern(tc.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			u, _ := url.Parse(tc.input)
+
+			if actual := pattern.Match(u); actual != tc.expect {
+				t.Errorf("%s.Match(%s) = %t, want %t", tc.pattern, tc.input, actual, tc.expect)
+			}
+		})
+	}
+}
+
+func TestEngine_Check(t *testing.T) {
+	t.Parallel()
+
+	rules, err := policy.Parse(strings.NewReader(`# personal hub
+allow topic *.example.com
+deny topic example.com/private/*
+allow callback example.net
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := policy.NewEngine(rules)
+
+	for name, tc := range map[string]struct {
+		topic, callback string
+		expect          error
+	}{
+		"allowed": {topic: "https://example.com/feed", callback: "https://example.net/cb"},
+		"topic": {
+			topic:    "https://example.org/feed",
+			callback: "https://example.net/cb",
+			expect:   domain.ErrReasonPolicy,
+		},
+		"denied": {
+			topic:    "https://example.com/private/feed",
+			callback: "https://example.net/cb",
+			expect:   domain.ErrReasonPolicy,
+		},
+		"callback": {
+			topic:    "https://example.com/feed",
+			callback: "https://example.org/cb",
+			expect:   domain.ErrReasonCallback,
+		},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			topic, _ := url.Parse(tc.topic)
+			callback, _ := url.Parse(tc.callback)
+
+			if err := engine.Check(topic, callback); !errors.Is(err, tc.expect) {
+				t.Errorf("want %v error, got %v", tc.expect, err)
+			}
+		})
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		t.Parallel()
+
+		reloaded := policy.NewEngine(rules)
+		reloaded.Store(nil)
+
+		topic, _ := url.Parse("https://example.org/feed")
+		callback, _ := url.Parse("https://example.org/cb")
+
+		if err := reloaded.Check(topic, callback); err != nil {
+			t.Errorf("want nil error, got %v", err)
+		}
+	})
+}
+
+func TestParse_Syntax(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"allow topic", "permit topic example.com", "allow topic exa*mple.com"} {
+		if _, err := policy.Parse(strings.NewReader(input)); !errors.Is(err, policy.ErrSyntax) {
+			t.Errorf("Parse(%s) = %v, want %v", input, err, policy.ErrSyntax)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+)
+
+type Repository interface {
+	Create(ctx context.Context, p domain.Publisher) error
+	Get(ctx context.Context, id string) (*domain.Publisher, error)
+	// GetByToken returns publisher by checksum of it's bearer token.
+	GetByToken(ctx context.Context, token string) (*domain.Publisher, error)
+	Fetch(ctx context.Context) ([]domain.Publisher, error)
+	Delete(ctx context.Context, id string) (bool, error)
+}
+
+var (
+	ErrExist    = errors.New("publisher already exists")
+	ErrNotExist = errors.New("publisher does not exist")
+)
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/publisher"
+)
+
+type memoryPublisherRepository struct {
+	mutex      *sync.RWMutex
+	publishers map[string]domain.Publisher
+}
+
+func NewMemoryPublisherRepository() publisher.Repository {
+	return &memoryPublisherRepository{
+		mutex:      new(sync.RWMutex),
+		publishers: make(map[string]domain.Publisher),
+	}
+}
+
+func (repo *memoryPublisherRepository) Create(_ context.Context, p domain.Publisher) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, ok := repo.publishers[p.ID]; ok {
+		return fmt.Errorf("cannot create publisher: %w", publisher.ErrExist)
+	}
+
+	repo.publishers[p.ID] = p
+
+	return nil
+}
+
+func (repo *memoryPublisherRepository) Get(_ context.Context, id string) (*domain.Publisher, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	if out, ok := repo.publishers[id]; ok {
+		return &out, nil
+	}
+
+	return nil, publisher.ErrNotExist
+}
+
+func (repo *memoryPublisherRepository) GetByToken(_ context.Context, token string) (*domain.Publisher, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, p := range repo.publishers {
+		if p.Token == token {
+			return &p, nil
+		}
+	}
+
+	return nil, publisher.ErrNotExist
+}
+
+func (repo *memoryPublisherRepository) Fetch(_ context.Context) ([]domain.Publisher, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Publisher, 0, len(repo.publishers))
+
+	for _, p := range repo.publishers {
+		out = append(out, p)
+	}
+
+	return out, nil
+}
+
+func (repo *memoryPublisherRepository) Delete(_ context.Context, id string) (bool, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if _, ok := repo.publishers[id]; !ok {
+		return false, nil
+	}
+
+	delete(repo.publishers, id)
+
+	return true, nil
+}
@@ -0,0 +1,289 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/publisher"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+type (
+	Publisher struct {
+		CreatedAt DateTime `db:"created_at"`
+		ID        string   `db:"id"`
+		Token     string   `db:"token"`
+		Secret    Secret   `db:"secret"`
+		Prefixes  Prefixes `db:"prefixes"`
+	}
+
+	DateTime struct {
+		DateTime time.Time
+		Valid    bool
+	}
+
+	Secret struct {
+		Secret domain.Secret
+		Valid  bool
+	}
+
+	// Prefixes is a whitespace separated list of URL prefixes.
+	Prefixes struct {
+		Prefixes []*url.URL
+		Valid    bool
+	}
+
+	sqlitePublisherRepository struct {
+		create      *sqlx.NamedStmt
+		read        *sqlx.Stmt
+		readByToken *sqlx.Stmt
+		fetch       *sqlx.Stmt
+		delete      *sqlx.Stmt
+	}
+)
+
+const (
+	table      string = "publishers"
+	queryTable string = `CREATE TABLE IF NOT EXISTS ` + table + ` (
+		created_at DATETIME,
+		id TEXT PRIMARY KEY,
+		token TEXT UNIQUE,
+		secret TEXT,
+		prefixes TEXT DEFAULT ''
+	)`
+	queryCreate string = `INSERT INTO ` + table + ` (created_at, id, token, secret, prefixes)
+		VALUES (:created_at, :id, :token, :secret, :prefixes);`
+	queryFetch       string = `SELECT * FROM ` + table + `;`
+	queryRead        string = `SELECT * FROM ` + table + ` WHERE id = ?;`
+	queryReadByToken string = `SELECT * FROM ` + table + ` WHERE token = ?;`
+	queryDelete      string = `DELETE FROM ` + table + ` WHERE id = ?;`
+)
+
+func NewSQLitePublisherRepository(db *sqlx.DB) (publisher.Repository, error) {
+	out := new(sqlitePublisherRepository)
+
+	var err error
+	if _, err = db.Exec(queryTable); err != nil {
+		return nil, fmt.Errorf("publisher: sqlite: cannot prepare table: %w", err)
+	}
+
+	if out.create, err = db.PrepareNamed(queryCreate); err != nil {
+		return nil, fmt.Errorf("publisher: sqlite: cannot create prepared named publisher statement: %w", err)
+	}
+
+	for q, dst := range map[string]**sqlx.Stmt{
+		queryDelete:      &out.delete,
+		queryFetch:       &out.fetch,
+		queryRead:        &out.read,
+		queryReadByToken: &out.readByToken,
+	} {
+		if *dst, err = db.Preparex(q); err != nil {
+			return nil, fmt.Errorf("publisher: sqlite: cannot create prepared publisher statement: %w", err)
+		}
+	}
+
+	return out, nil
+}
+
+func (repo *sqlitePublisherRepository) Create(ctx context.Context, p domain.Publisher) error {
+	if _, err := repo.Get(ctx, p.ID); err == nil {
+		return fmt.Errorf("publisher: sqlite: cannot create publisher: %w", publisher.ErrExist)
+	} else if !errors.Is(err, publisher.ErrNotExist) {
+		return fmt.Errorf("publisher: sqlite: cannot check publisher: %w", err)
+	}
+
+	row := new(Publisher)
+	row.bind(p)
+
+	if _, err := repo.create.ExecContext(ctx, row); err != nil {
+		return fmt.Errorf("publisher: sqlite: cannot create publisher: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *sqlitePublisherRepository) Get(ctx context.Context, id string) (*domain.Publisher, error) {
+	return repo.get(ctx, repo.read, id)
+}
+
+func (repo *sqlitePublisherRepository) GetByToken(ctx context.Context, token string) (*domain.Publisher, error) {
+	return repo.get(ctx, repo.readByToken, token)
+}
+
+func (repo *sqlitePublisherRepository) Fetch(ctx context.Context) ([]domain.Publisher, error) {
+	rows, err := repo.fetch.QueryxContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("publisher: sqlite: cannot fetch publishers: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]domain.Publisher, 0)
+
+	for rows.Next() {
+		row := new(Publisher)
+		if err = rows.StructScan(row); err != nil {
+			return nil, fmt.Errorf("publisher: sqlite: cannot scan publishers row: %w", err)
+		}
+
+		var p domain.Publisher
+		row.populate(&p)
+
+		out = append(out, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("publisher: sqlite: cannot read publishers rows: %w", err)
+	}
+
+	return out, nil
+}
+
+func (repo *sqlitePublisherRepository) Delete(ctx context.Context, id string) (bool, error) {
+	result, err := repo.delete.ExecContext(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("publisher: sqlite: cannot delete publisher: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("publisher: sqlite: cannot read affected deleted rows result: %w", err)
+	}
+
+	return count == 1, nil
+}
+
+func (repo *sqlitePublisherRepository) get(ctx context.Context, stmt *sqlx.Stmt, arg string) (*domain.Publisher,
+	error,
+) {
+	row := new(Publisher)
+	if err := stmt.GetContext(ctx, row, arg); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, publisher.ErrNotExist
+		}
+
+		return nil, fmt.Errorf("publisher: sqlite: cannot get publisher row: %w", err)
+	}
+
+	out := new(domain.Publisher)
+	row.populate(out)
+
+	return out, nil
+}
+
+func (p *Publisher) bind(src domain.Publisher) {
+	p.CreatedAt = NewDateTime(src.CreatedAt)
+	p.ID = src.ID
+	p.Token = src.Token
+	p.Secret = Secret{Secret: src.Secret, Valid: src.Secret.IsSet()}
+	p.Prefixes = Prefixes{Prefixes: src.Prefixes, Valid: len(src.Prefixes) > 0}
+}
+
+func (p Publisher) populate(dst *domain.Publisher) {
+	dst.CreatedAt = p.CreatedAt.DateTime
+	dst.ID = p.ID
+	dst.Token = p.Token
+	dst.Secret = p.Secret.Secret
+	dst.Prefixes = p.Prefixes.Prefixes
+}
+
+func (p *Prefixes) Scan(src any) error {
+	var value string
+
+	switch raw := src.(type) {
+	default:
+	case []byte:
+		value = string(raw)
+	case string:
+		value = raw
+	}
+
+	for _, v := range strings.Fields(value) {
+		u, err := url.Parse(v)
+		if err != nil {
+			return fmt.Errorf("Prefixes: cannot scan TEXT value as URL: %w", err)
+		}
+
+		p.Prefixes = append(p.Prefixes, u)
+	}
+
+	p.Valid = len(p.Prefixes) > 0
+
+	return nil
+}
+
+func (p Prefixes) Value() (driver.Value, error) {
+	if !p.Valid {
+		return "", nil
+	}
+
+	out := make([]string, len(p.Prefixes))
+	for i := range p.Prefixes {
+		out[i] = urlutil.Canonical(p.Prefixes[i]).String()
+	}
+
+	return strings.Join(out, " "), nil
+}
+
+func (s *Secret) Scan(src any) error {
+	var value string
+
+	switch raw := src.(type) {
+	default:
+	case []byte:
+		value = string(raw)
+	case string:
+		value = raw
+	}
+
+	parsed, err := domain.ParseSecret(value)
+	if err != nil {
+		return fmt.Errorf("Secret: cannot scan value as Secret: %w", err)
+	}
+
+	s.Secret = *parsed
+	s.Valid = true
+
+	return nil
+}
+
+func (s Secret) Value() (driver.Value, error) {
+	if !s.Valid {
+		return "", nil
+	}
+
+	return s.Secret.String(), nil
+}
+
+func NewDateTime(t time.Time) DateTime {
+	return DateTime{
+		DateTime: t,
+		Valid:    !t.IsZero(),
+	}
+}
+
+func (dt *DateTime) Scan(src any) error {
+	switch s := src.(type) {
+	case int64:
+		dt.DateTime = time.Unix(s, 0)
+		dt.Valid = true
+	}
+
+	return nil
+}
+
+func (dt DateTime) Value() (driver.Value, error) {
+	if !dt.Valid {
+		return int64(0), nil
+	}
+
+	return dt.DateTime.Unix(), nil
+}
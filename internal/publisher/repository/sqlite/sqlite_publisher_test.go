@@ -0,0 +1,82 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/publisher"
+	repository "source.toby3d.me/toby3d/hub/internal/publisher/repository/sqlite"
+)
+
+func Test(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLitePublisherRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := domain.TestPublisher(t)
+
+	// NOTE(toby3d): Create test.
+	if err = repo.Create(context.Background(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repo.Create(context.Background(), *in); !errors.Is(err, publisher.ErrExist) {
+		t.Errorf("want %v error, got %v", publisher.ErrExist, err)
+	}
+
+	// NOTE(toby3d): Get test depends from Create.
+	actual, err := repo.Get(context.Background(), in.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, in, cmp.AllowUnexported(domain.Secret{})); diff != "" {
+		t.Error(diff)
+	}
+
+	// NOTE(toby3d): GetByToken test depends from Create.
+	if actual, err = repo.GetByToken(context.Background(), in.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.ID != in.ID {
+		t.Errorf("want '%s', got '%s'", in.ID, actual.ID)
+	}
+
+	// NOTE(toby3d): Fetch test depends from Create.
+	publishers, err := repo.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(publishers) != 1 {
+		t.Errorf("want %d publishers, got %d", 1, len(publishers))
+	}
+
+	// NOTE(toby3d): Delete test depends from Create.
+	ok, err := repo.Delete(context.Background(), in.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Errorf("want %t, got %t", true, ok)
+	}
+
+	if _, err = repo.Get(context.Background(), in.ID); !errors.Is(err, publisher.ErrNotExist) {
+		t.Errorf("want %v error, got %v", publisher.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,26 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+)
+
+type UseCase interface {
+	// Register creates a new trusted publisher of topics under prefixes and
+	// returns it with it's bearer token. The token is not stored, so it
+	// cannot be returned again.
+	Register(ctx context.Context, prefixes []*url.URL) (*domain.Publisher, string, error)
+	// Unregister removes trusted publisher.
+	Unregister(ctx context.Context, id string) (bool, error)
+	// Authenticate returns publisher of the bearer token.
+	Authenticate(ctx context.Context, token string) (*domain.Publisher, error)
+	// Verify returns publisher id if signature is a valid "<algorithm>=<hex
+	// digest>" HMAC signature of message computed with it's secret, see
+	// domain.SignedMessage.
+	Verify(ctx context.Context, id, signature string, message []byte) (*domain.Publisher, error)
+}
+
+var ErrUnauthorized = errors.New("publisher credentials are invalid")
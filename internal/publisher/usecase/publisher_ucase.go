@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/publisher"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+type (
+	NewPublisherUseCaseParams struct {
+		Publishers publisher.Repository
+	}
+
+	publisherUseCase struct {
+		publishers publisher.Repository
+	}
+)
+
+// Sizes of random publisher identifiers and credentials in bytes.
+const (
+	idLength     int = 8
+	tokenLength  int = 32
+	secretLength int = 32
+)
+
+func NewPublisherUseCase(params NewPublisherUseCaseParams) publisher.UseCase {
+	return &publisherUseCase{
+		publishers: params.Publishers,
+	}
+}
+
+func (ucase *publisherUseCase) Register(ctx context.Context, prefixes []*url.URL) (*domain.Publisher, string,
+	error,
+) {
+	id, err := random(idLength)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot generate publisher id: %w", err)
+	}
+
+	token, err := random(tokenLength)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot generate publisher token: %w", err)
+	}
+
+	rawSecret, err := random(secretLength)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot generate publisher secret: %w", err)
+	}
+
+	secret, err := domain.ParseSecret(rawSecret)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot generate publisher secret: %w", err)
+	}
+
+	out := &domain.Publisher{
+		CreatedAt: time.Now().UTC().Round(time.Second),
+		Prefixes:  make([]*url.URL, 0, len(prefixes)),
+		ID:        id,
+		Token:     domain.NewContentHash([]byte(token)),
+		Secret:    *secret,
+	}
+
+	for i := range prefixes {
+		out.Prefixes = append(out.Prefixes, urlutil.Canonical(prefixes[i]))
+	}
+
+	if err = ucase.publishers.Create(ctx, *out); err != nil {
+		return nil, "", fmt.Errorf("cannot register publisher: %w", err)
+	}
+
+	return out, token, nil
+}
+
+func (ucase *publisherUseCase) Unregister(ctx context.Context, id string) (bool, error) {
+	ok, err := ucase.publishers.Delete(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("cannot unregister publisher: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (ucase *publisherUseCase) Authenticate(ctx context.Context, token string) (*domain.Publisher, error) {
+	// NOTE(toby3d): only checksums of tokens are stored, so lookup by the
+	// checksum also does not leak the token timing.
+	out, err := ucase.publishers.GetByToken(ctx, domain.NewContentHash([]byte(token)))
+	if err != nil {
+		if errors.Is(err, publisher.ErrNotExist) {
+			return nil, publisher.ErrUnauthorized
+		}
+
+		return nil, fmt.Errorf("cannot authenticate publisher: %w", err)
+	}
+
+	return out, nil
+}
+
+func (ucase *publisherUseCase) Verify(ctx context.Context, id, signature string, message []byte) (*domain.Publisher,
+	error,
+) {
+	rawAlg, digest, ok := strings.Cut(signature, "=")
+	if !ok {
+		return nil, fmt.Errorf("%w: signature MUST be in '<algorithm>=<digest>' format", publisher.ErrUnauthorized)
+	}
+
+	alg, err := domain.ParseAlgorithm(rawAlg)
+	if err != nil || alg == domain.AlgorithmSHA1 {
+		return nil, fmt.Errorf("%w: unsupported signature algorithm %s", publisher.ErrUnauthorized, rawAlg)
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decode signature digest", publisher.ErrUnauthorized)
+	}
+
+	out, err := ucase.publishers.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, publisher.ErrNotExist) {
+			return nil, publisher.ErrUnauthorized
+		}
+
+		return nil, fmt.Errorf("cannot verify publisher: %w", err)
+	}
+
+	if !out.Secret.IsSet() {
+		return nil, publisher.ErrUnauthorized
+	}
+
+	h := hmac.New(alg.Hash, []byte(out.Secret.String()))
+	h.Write(message)
+
+	if !hmac.Equal(h.Sum(nil), expected) {
+		return nil, publisher.ErrUnauthorized
+	}
+
+	return out, nil
+}
+
+// random returns a base64url encoded string of n cryptographically random
+// bytes.
+func random(n int) (string, error) {
+	src := make([]byte, n)
+	if _, err := rand.Read(src); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(src), nil
+}
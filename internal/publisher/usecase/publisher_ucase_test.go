@@ -0,0 +1,86 @@
+package usecase_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"testing"
+
+	"source.toby3d.me/toby3d/hub/internal/publisher"
+	"source.toby3d.me/toby3d/hub/internal/publisher/repository/memory"
+	"source.toby3d.me/toby3d/hub/internal/publisher/usecase"
+)
+
+func TestPublisherUseCase_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	ucase := usecase.NewPublisherUseCase(usecase.NewPublisherUseCaseParams{
+		Publishers: memory.NewMemoryPublisherRepository(),
+	})
+
+	p, token, err := ucase.Register(context.Background(), []*url.URL{{Scheme: "https", Host: "example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Token == token {
+		t.Error("token must not be stored as is")
+	}
+
+	actual, err := ucase.Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.ID != p.ID {
+		t.Errorf("want '%s', got '%s'", p.ID, actual.ID)
+	}
+
+	if _, err = ucase.Authenticate(context.Background(), "invalid"); !errors.Is(err, publisher.ErrUnauthorized) {
+		t.Errorf("want %v error, got %v", publisher.ErrUnauthorized, err)
+	}
+}
+
+func TestPublisherUseCase_Verify(t *testing.T) {
+	t.Parallel()
+
+	ucase := usecase.NewPublisherUseCase(usecase.NewPublisherUseCaseParams{
+		Publishers: memory.NewMemoryPublisherRepository(),
+	})
+
+	p, _, err := ucase.Register(context.Background(), []*url.URL{{Scheme: "https", Host: "example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte("hub.mode=publish&hub.topic=https%3A%2F%2Fexample.com%2F")
+	h := hmac.New(sha256.New, []byte(p.Secret.String()))
+	h.Write(body)
+	signature := "sha256=" + hex.EncodeToString(h.Sum(nil))
+
+	for name, tc := range map[string]struct {
+		id, signature string
+		body          []byte
+		expect        error
+	}{
+		"valid":     {id: p.ID, signature: signature, body: body},
+		"body":      {id: p.ID, signature: signature, body: []byte("hub.mode=publish"), expect: publisher.ErrUnauthorized},
+		"publisher": {id: "unknown", signature: signature, body: body, expect: publisher.ErrUnauthorized},
+		"algorithm": {id: p.ID, signature: "md5=" + signature[7:], body: body, expect: publisher.ErrUnauthorized},
+		"format":    {id: p.ID, signature: signature[7:], body: body, expect: publisher.ErrUnauthorized},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := ucase.Verify(context.Background(), tc.id, tc.signature, tc.body); !errors.Is(err,
+				tc.expect) {
+				t.Errorf("want %v error, got %v", tc.expect, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+)
+
+type (
+	UpdateFunc func(job *domain.Job) (*domain.Job, error)
+
+	Repository interface {
+		Create(ctx context.Context, suid domain.SUID, job domain.Job) error
+		Get(ctx context.Context, suid domain.SUID) (*domain.Job, error)
+		// Fetch returns pending jobs which are scheduled at or before ts.
+		Fetch(ctx context.Context, ts time.Time) ([]domain.Job, error)
+		Update(ctx context.Context, suid domain.SUID, update UpdateFunc) error
+		Delete(ctx context.Context, suid domain.SUID) (bool, error)
+		// Move re-keys all jobs of the from topic to the to topic,
+		// replacing the existing ones.
+		Move(ctx context.Context, from, to *url.URL) error
+	}
+)
+
+var (
+	ErrExist    = errors.New("job already exists")
+	ErrNotExist = errors.New("job does not exist")
+)
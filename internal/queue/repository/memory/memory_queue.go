@@ -0,0 +1,137 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/queue"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+type memoryQueueRepository struct {
+	mutex *sync.RWMutex
+	jobs  map[string]domain.Job
+}
+
+func NewMemoryQueueRepository() queue.Repository {
+	return &memoryQueueRepository{
+		mutex: new(sync.RWMutex),
+		jobs:  make(map[string]domain.Job),
+	}
+}
+
+func (repo *memoryQueueRepository) Create(ctx context.Context, suid domain.SUID, j domain.Job) error {
+	if _, err := repo.Get(ctx, suid); err != nil {
+		if !errors.Is(err, queue.ErrNotExist) {
+			return fmt.Errorf("cannot create job: %w", err)
+		}
+	} else {
+		return fmt.Errorf("cannot create job: %w", queue.ErrExist)
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.jobs[key(suid)] = j
+
+	return nil
+}
+
+func (repo *memoryQueueRepository) Get(_ context.Context, suid domain.SUID) (*domain.Job, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	if out, ok := repo.jobs[key(suid)]; ok {
+		return &out, nil
+	}
+
+	return nil, queue.ErrNotExist
+}
+
+func (repo *memoryQueueRepository) Fetch(_ context.Context, ts time.Time) ([]domain.Job, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Job, 0)
+
+	for _, j := range repo.jobs {
+		if !j.Ready(ts) {
+			continue
+		}
+
+		out = append(out, j)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ScheduledAt.Before(out[j].ScheduledAt) })
+
+	return out, nil
+}
+
+func (repo *memoryQueueRepository) Update(ctx context.Context, suid domain.SUID, update queue.UpdateFunc) error {
+	in, err := repo.Get(ctx, suid)
+	if err != nil {
+		return fmt.Errorf("cannot update job: %w", err)
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	out, err := update(in)
+	if err != nil {
+		return fmt.Errorf("cannot update job: %w", err)
+	}
+
+	repo.jobs[key(suid)] = *out
+
+	return nil
+}
+
+func (repo *memoryQueueRepository) Delete(ctx context.Context, suid domain.SUID) (bool, error) {
+	if _, err := repo.Get(ctx, suid); err != nil {
+		if !errors.Is(err, queue.ErrNotExist) {
+			return false, fmt.Errorf("cannot delete job: %w", err)
+		}
+
+		return false, nil
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	delete(repo.jobs, key(suid))
+
+	return true, nil
+}
+
+func key(suid domain.SUID) string {
+	return urlutil.Canonical(suid.Topic()).String() + " " + urlutil.Canonical(suid.Callback()).String()
+}
+
+func (repo *memoryQueueRepository) Move(_ context.Context, from, to *url.URL) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for k, j := range repo.jobs {
+		if !sameURL(j.Topic, from) {
+			continue
+		}
+
+		delete(repo.jobs, k)
+
+		j.Topic = to
+		repo.jobs[key(j.SUID())] = j
+	}
+
+	return nil
+}
+
+// sameURL reports whether a and b are equivalent URLs.
+func sameURL(a, b *url.URL) bool {
+	return urlutil.Canonical(a).String() == urlutil.Canonical(b).String()
+}
@@ -0,0 +1,384 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/queue"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+type (
+	Job struct {
+		CreatedAt   DateTime `db:"created_at"`
+		UpdatedAt   DateTime `db:"updated_at"`
+		ScheduledAt DateTime `db:"scheduled_at"`
+		Version     DateTime `db:"version"`
+		VersionID   uint64   `db:"version_id"`
+		Replay      bool     `db:"replay"`
+		Topic       URL      `db:"topic"`
+		Callback    URL      `db:"callback"`
+		Error       string   `db:"error"`
+		Attempts    uint     `db:"attempts"`
+		Status      int      `db:"status"`
+		State       State    `db:"state"`
+	}
+
+	DateTime struct {
+		DateTime time.Time
+		Valid    bool
+	}
+
+	URL struct {
+		URL   *url.URL
+		Valid bool
+	}
+
+	State struct {
+		State domain.JobState
+		Valid bool
+	}
+
+	sqliteQueueRepository struct {
+		create *sqlx.NamedStmt
+		update *sqlx.NamedStmt
+		read   *sqlx.Stmt
+		fetch  *sqlx.Stmt
+		delete *sqlx.Stmt
+		move   *sqlx.Stmt
+	}
+)
+
+const (
+	table      string = "queue"
+	queryTable string = `CREATE TABLE IF NOT EXISTS ` + table + ` (
+		created_at DATETIME,
+		updated_at DATETIME,
+		scheduled_at DATETIME,
+		version DATETIME,
+		topic TEXT,
+		callback TEXT,
+		error TEXT,
+		attempts INTEGER,
+		status INTEGER,
+		state TEXT,
+		version_id INTEGER DEFAULT 0,
+		replay INTEGER DEFAULT 0,
+		PRIMARY KEY (topic, callback)
+	)`
+	queryIndex  string = `CREATE INDEX IF NOT EXISTS idx_queue ON ` + table + ` (state, scheduled_at);`
+	queryCreate string = `INSERT INTO ` + table + ` (created_at, updated_at, scheduled_at, version, topic, ` +
+		`callback, error, attempts, status, state, version_id, replay)
+		VALUES (:created_at, :updated_at, :scheduled_at, :version, :topic, :callback, :error, :attempts, ` +
+		`:status, :state, :version_id, :replay);`
+	queryFetch string = `SELECT * FROM ` + table + ` WHERE state = 'pending' AND scheduled_at <= ?
+		ORDER BY scheduled_at;`
+	queryRead   string = `SELECT * FROM ` + table + ` WHERE topic = ? AND callback = ?;`
+	queryUpdate string = `UPDATE ` + table + `
+				SET updated_at = :updated_at,
+					scheduled_at = :scheduled_at,
+					version = :version,
+					version_id = :version_id,
+					replay = :replay,
+					error = :error,
+					attempts = :attempts,
+					status = :status,
+					state = :state
+				WHERE topic = :topic AND callback = :callback;`
+	queryDelete string = `DELETE FROM ` + table + ` WHERE topic = ? AND callback = ?;`
+	queryMove   string = `UPDATE OR REPLACE ` + table + ` SET topic = ? WHERE topic = ?;`
+)
+
+func NewSQLiteQueueRepository(db *sqlx.DB) (queue.Repository, error) {
+	out := new(sqliteQueueRepository)
+
+	var err error
+	if _, err = db.Exec(queryTable); err != nil {
+		return nil, fmt.Errorf("queue: sqlite: cannot prepare table: %w", err)
+	}
+
+	if err = sqlutil.AddColumns(db, table,
+		sqlutil.Column{Name: "version_id", Definition: "INTEGER DEFAULT 0"},
+		sqlutil.Column{Name: "replay", Definition: "INTEGER DEFAULT 0"},
+	); err != nil {
+		return nil, fmt.Errorf("queue: sqlite: cannot migrate table: %w", err)
+	}
+
+	// NOTE(toby3d): previous versions of the hub stored URLs as is, so
+	// equivalent URLs of the same resource may be stored in separate rows.
+	if err = sqlutil.Migrate(db, table+"_canonical_urls", func(tx *sqlx.Tx) error {
+		return sqlutil.MergeDuplicates(tx, table, "updated_at DESC", urlutil.CanonicalString, "topic",
+			"callback")
+	}); err != nil {
+		return nil, fmt.Errorf("queue: sqlite: cannot migrate table: %w", err)
+	}
+
+	for q, dst := range map[string]**sqlx.NamedStmt{
+		queryCreate: &out.create,
+		queryUpdate: &out.update,
+	} {
+		if *dst, err = db.PrepareNamed(q); err != nil {
+			return nil, fmt.Errorf("queue: sqlite: cannot create prepared named job statement: %w", err)
+		}
+	}
+
+	for q, dst := range map[string]**sqlx.Stmt{
+		queryDelete: &out.delete,
+		queryMove:   &out.move,
+		queryFetch:  &out.fetch,
+		queryRead:   &out.read,
+	} {
+		if *dst, err = db.Preparex(q); err != nil {
+			return nil, fmt.Errorf("queue: sqlite: cannot create prepared job statement: %w", err)
+		}
+	}
+
+	if _, err = db.Exec(queryIndex); err != nil {
+		return nil, fmt.Errorf("queue: sqlite: cannot create index: %w", err)
+	}
+
+	return out, nil
+}
+
+func (repo *sqliteQueueRepository) Create(ctx context.Context, suid domain.SUID, j domain.Job) error {
+	if _, err := repo.Get(ctx, suid); err == nil {
+		return fmt.Errorf("queue: sqlite: cannot create job: %w", queue.ErrExist)
+	} else if !errors.Is(err, queue.ErrNotExist) {
+		return fmt.Errorf("queue: sqlite: cannot check job: %w", err)
+	}
+
+	row := new(Job)
+	row.bind(j)
+
+	if _, err := repo.create.ExecContext(ctx, row); err != nil {
+		return fmt.Errorf("queue: sqlite: cannot create job: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *sqliteQueueRepository) Get(ctx context.Context, suid domain.SUID) (*domain.Job, error) {
+	row := new(Job)
+	if err := repo.read.GetContext(ctx, row, urlutil.Canonical(suid.Topic()).String(),
+		urlutil.Canonical(suid.Callback()).String()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, queue.ErrNotExist
+		}
+
+		return nil, fmt.Errorf("queue: sqlite: cannot get job row: %w", err)
+	}
+
+	out := new(domain.Job)
+	row.populate(out)
+
+	return out, nil
+}
+
+func (repo *sqliteQueueRepository) Fetch(ctx context.Context, ts time.Time) ([]domain.Job, error) {
+	rows, err := repo.fetch.QueryxContext(ctx, ts.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("queue: sqlite: cannot fetch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]domain.Job, 0)
+
+	for rows.Next() {
+		row := new(Job)
+		if err = rows.StructScan(row); err != nil {
+			return nil, fmt.Errorf("queue: sqlite: cannot scan jobs row: %w", err)
+		}
+
+		var j domain.Job
+		row.populate(&j)
+
+		out = append(out, j)
+	}
+
+	return out, nil
+}
+
+func (repo *sqliteQueueRepository) Update(ctx context.Context, suid domain.SUID, update queue.UpdateFunc) error {
+	in, err := repo.Get(ctx, suid)
+	if err != nil {
+		return fmt.Errorf("queue: sqlite: cannot find updating job: %w", err)
+	}
+
+	out, err := update(in)
+	if err != nil {
+		return fmt.Errorf("queue: sqlite: cannot update job: %w", err)
+	}
+
+	row := new(Job)
+	row.bind(*out)
+
+	if _, err = repo.update.ExecContext(ctx, row); err != nil {
+		return fmt.Errorf("queue: sqlite: cannot update job row: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *sqliteQueueRepository) Move(ctx context.Context, from, to *url.URL) error {
+	if _, err := sqlutil.Stmt(ctx, repo.move).ExecContext(ctx, urlutil.Canonical(to).String(),
+		urlutil.Canonical(from).String()); err != nil {
+		return fmt.Errorf("queue: sqlite: cannot move jobs: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *sqliteQueueRepository) Delete(ctx context.Context, suid domain.SUID) (bool, error) {
+	result, err := repo.delete.ExecContext(ctx, urlutil.Canonical(suid.Topic()).String(),
+		urlutil.Canonical(suid.Callback()).String())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("queue: sqlite: cannot delete job: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("queue: sqlite: cannot read affected deleted rows result: %w", err)
+	}
+
+	return count == 1, nil
+}
+
+func (j *Job) bind(src domain.Job) {
+	j.CreatedAt = NewDateTime(src.CreatedAt)
+	j.UpdatedAt = NewDateTime(src.UpdatedAt)
+	j.ScheduledAt = NewDateTime(src.ScheduledAt)
+	j.Version = NewDateTime(src.Version)
+	j.VersionID = src.VersionID
+	j.Replay = src.Replay
+	j.Topic = NewURL(src.Topic)
+	j.Callback = NewURL(src.Callback)
+	j.Error = src.Error
+	j.Attempts = src.Attempts
+	j.Status = src.Status
+	j.State = NewState(src.State)
+}
+
+func (j Job) populate(dst *domain.Job) {
+	dst.CreatedAt = j.CreatedAt.DateTime
+	dst.UpdatedAt = j.UpdatedAt.DateTime
+	dst.ScheduledAt = j.ScheduledAt.DateTime
+	dst.Version = j.Version.DateTime
+	dst.VersionID = j.VersionID
+	dst.Replay = j.Replay
+	dst.Topic = j.Topic.URL
+	dst.Callback = j.Callback.URL
+	dst.Error = j.Error
+	dst.Attempts = j.Attempts
+	dst.Status = j.Status
+	dst.State = j.State.State
+}
+
+func NewURL(u *url.URL) URL {
+	return URL{
+		URL:   u,
+		Valid: u != nil,
+	}
+}
+
+func (u *URL) Scan(src any) error {
+	var err error
+
+	switch s := src.(type) {
+	case []byte:
+		if u.URL, err = url.Parse(string(s)); err != nil {
+			return fmt.Errorf("URL: cannot scan BLOB value as URL: %w", err)
+		}
+
+		u.Valid = true
+	case string:
+		if u.URL, err = url.Parse(s); err != nil {
+			return fmt.Errorf("URL: cannot scan TEXT value as URL: %w", err)
+		}
+
+		u.Valid = true
+	}
+
+	return nil
+}
+
+func (u URL) Value() (driver.Value, error) {
+	if !u.Valid {
+		return "", nil
+	}
+
+	return urlutil.Canonical(u.URL).String(), nil
+}
+
+func NewDateTime(t time.Time) DateTime {
+	return DateTime{
+		DateTime: t,
+		Valid:    !t.IsZero(),
+	}
+}
+
+func (dt *DateTime) Scan(src any) error {
+	switch s := src.(type) {
+	case int64:
+		dt.DateTime = time.Unix(s, 0).UTC()
+		dt.Valid = true
+	}
+
+	return nil
+}
+
+func (dt DateTime) Value() (driver.Value, error) {
+	if !dt.Valid {
+		return int64(0), nil
+	}
+
+	return dt.DateTime.Unix(), nil
+}
+
+func NewState(s domain.JobState) State {
+	return State{
+		State: s,
+		Valid: s != domain.JobStateUnd,
+	}
+}
+
+func (s *State) Scan(src any) error {
+	var value string
+
+	switch raw := src.(type) {
+	default:
+	case []byte:
+		value = string(raw)
+	case string:
+		value = raw
+	}
+
+	var err error
+	if s.State, err = domain.ParseJobState(value); err != nil {
+		return fmt.Errorf("State: cannot scan value as JobState: %w", err)
+	}
+
+	s.Valid = true
+
+	return nil
+}
+
+func (s State) Value() (driver.Value, error) {
+	if !s.Valid {
+		return "", nil
+	}
+
+	return s.State.String(), nil
+}
@@ -0,0 +1,94 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/queue"
+	repository "source.toby3d.me/toby3d/hub/internal/queue/repository/sqlite"
+)
+
+func Test(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLiteQueueRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := domain.TestJob(t)
+
+	// NOTE(toby3d): Create test.
+	if err = repo.Create(context.Background(), job.SUID(), *job); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repo.Create(context.Background(), job.SUID(), *job); !errors.Is(err, queue.ErrExist) {
+		t.Errorf("want %v error, got %v", queue.ErrExist, err)
+	}
+
+	// NOTE(toby3d): Get test depends from Create.
+	actual, err := repo.Get(context.Background(), job.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, job, cmp.AllowUnexported(domain.JobState{})); diff != "" {
+		t.Error(diff)
+	}
+
+	// NOTE(toby3d): Fetch test depends from Create.
+	jobs, err := repo.Fetch(context.Background(), job.ScheduledAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 1 {
+		t.Errorf("want %d ready jobs, got %d", 1, len(jobs))
+	}
+
+	// NOTE(toby3d): Update test depends from Create.
+	next := job.ScheduledAt.Add(time.Minute)
+
+	if err = repo.Update(context.Background(), job.SUID(), func(tx *domain.Job) (*domain.Job, error) {
+		tx.Attempts++
+		tx.ScheduledAt = next
+
+		return tx, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if jobs, err = repo.Fetch(context.Background(), job.ScheduledAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(jobs) != 0 {
+		t.Errorf("want %d ready jobs, got %d", 0, len(jobs))
+	}
+
+	// NOTE(toby3d): Delete test depends from Create.
+	ok, err := repo.Delete(context.Background(), job.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Errorf("want %t, got %t", true, ok)
+	}
+
+	if _, err = repo.Get(context.Background(), job.SUID()); !errors.Is(err, queue.ErrNotExist) {
+		t.Errorf("want %v error, got %v", queue.ErrNotExist, err)
+	}
+}
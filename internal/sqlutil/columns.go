@@ -0,0 +1,46 @@
+package sqlutil
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Column describes a table column which was added after the table creation.
+type Column struct {
+	Name       string
+	Definition string
+}
+
+// AddColumns adds missing columns into the existing table created by previous
+// versions of the hub, so "CREATE TABLE IF NOT EXISTS" queries can be extended
+// without breaking already deployed databases.
+func AddColumns(db *sqlx.DB, table string, columns ...Column) error {
+	existing := make([]string, 0)
+	if err := db.Select(&existing, `SELECT name FROM pragma_table_info(?);`, table); err != nil {
+		return fmt.Errorf("cannot read %s table columns: %w", table, err)
+	}
+
+	for _, column := range columns {
+		if contains(existing, column.Name) {
+			continue
+		}
+
+		if _, err := db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column.Name + ` ` +
+			column.Definition + `;`); err != nil {
+			return fmt.Errorf("cannot add %s column into %s table: %w", column.Name, table, err)
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, target string) bool {
+	for i := range list {
+		if list[i] == target {
+			return true
+		}
+	}
+
+	return false
+}
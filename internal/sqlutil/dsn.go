@@ -0,0 +1,23 @@
+package sqlutil
+
+import (
+	"strconv"
+	"strings"
+)
+
+// BusyTimeout is a time in milliseconds which connection waits for a database
+// lock held by another connection before it fails with SQLITE_BUSY.
+const BusyTimeout int = 5000
+
+// DSN returns data source name of database on path with options which allow
+// concurrent writers: they wait for each other up to BusyTimeout instead of
+// failing, and transactions take a write lock upfront, so they never deadlock
+// by upgrading read locks.
+func DSN(path string) string {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return path + separator + "_pragma=busy_timeout(" + strconv.Itoa(BusyTimeout) + ")&_txlock=immediate"
+}
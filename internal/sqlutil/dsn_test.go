@@ -0,0 +1,74 @@
+package sqlutil_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+)
+
+func TestDSN(t *testing.T) {
+	t.Parallel()
+
+	db := sqlx.MustOpen("sqlite", sqlutil.DSN(filepath.Join(t.TempDir(), "testing.db")))
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.MustExec(`CREATE TABLE counters (id INTEGER PRIMARY KEY, value INTEGER);
+		INSERT INTO counters (id, value) VALUES (1, 0);`)
+
+	read, err := db.Preparex(`SELECT value FROM counters WHERE id = 1;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write, err := db.Preparex(`UPDATE counters SET value = ? WHERE id = 1;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const writers, writes = 16, 10
+
+	transactor := sqlutil.NewTransactor(db)
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < writes; j++ {
+				// NOTE(toby3d): read-modify-write transactions must not
+				// fail with SQLITE_BUSY or lose concurrent writes.
+				if err := transactor.Transaction(context.Background(), func(ctx context.Context) error {
+					var value int
+					if err := sqlutil.Stmt(ctx, read).GetContext(ctx, &value); err != nil {
+						return err
+					}
+
+					_, err := sqlutil.Stmt(ctx, write).ExecContext(ctx, value+1)
+
+					return err
+				}); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	var value int
+	if err = db.Get(&value, `SELECT value FROM counters WHERE id = 1;`); err != nil {
+		t.Fatal(err)
+	}
+
+	if value != writers*writes {
+		t.Errorf("want %d writes, got %d", writers*writes, value)
+	}
+}
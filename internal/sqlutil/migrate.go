@@ -0,0 +1,138 @@
+package sqlutil
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const queryMigrations string = `CREATE TABLE IF NOT EXISTS migrations (
+	name TEXT PRIMARY KEY,
+	applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+// Migrate runs fn in a transaction only once per database: applied migrations
+// are remembered by their names.
+func Migrate(db *sqlx.DB, name string, fn func(tx *sqlx.Tx) error) error {
+	if _, err := db.Exec(queryMigrations); err != nil {
+		return fmt.Errorf("cannot prepare migrations table: %w", err)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("cannot begin %s migration: %w", name, err)
+	}
+	// NOTE(toby3d): rollback is a no-op after commit.
+	defer func() { _ = tx.Rollback() }()
+
+	var applied int
+	if err = tx.Get(&applied, `SELECT COUNT(*) FROM migrations WHERE name = ?;`, name); err != nil {
+		return fmt.Errorf("cannot check %s migration: %w", name, err)
+	}
+
+	if applied > 0 {
+		return nil
+	}
+
+	if err = fn(tx); err != nil {
+		return fmt.Errorf("cannot apply %s migration: %w", name, err)
+	}
+
+	if _, err = tx.Exec(`INSERT INTO migrations (name) VALUES (?);`, name); err != nil {
+		return fmt.Errorf("cannot record %s migration: %w", name, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit %s migration: %w", name, err)
+	}
+
+	return nil
+}
+
+// MergeDuplicates rewrites keys columns of table rows into their normalized
+// form and deletes rows which keys became equal to the keys of another one. The
+// first row in the order wins, so order must be an ORDER BY expression, such as
+// "updated_at DESC".
+func MergeDuplicates(tx *sqlx.Tx, table, order string, normalize func(string) string, keys ...string) error {
+	rows, err := tx.Query(`SELECT rowid, ` + strings.Join(keys, ", ") + ` FROM ` + table + ` ORDER BY ` +
+		order + `;`)
+	if err != nil {
+		return fmt.Errorf("cannot read %s table keys: %w", table, err)
+	}
+
+	type row struct {
+		values []string
+		id     int64
+		dirty  bool
+	}
+
+	seen := make(map[string]struct{})
+	winners, losers := make([]row, 0), make([]int64, 0)
+
+	for rows.Next() {
+		r := row{values: make([]string, len(keys))}
+		raw := make([]sql.NullString, len(keys))
+		dst := []any{&r.id}
+
+		for i := range raw {
+			dst = append(dst, &raw[i])
+		}
+
+		if err = rows.Scan(dst...); err != nil {
+			_ = rows.Close()
+
+			return fmt.Errorf("cannot scan %s table keys: %w", table, err)
+		}
+
+		for i := range raw {
+			r.values[i] = normalize(raw[i].String)
+			r.dirty = r.dirty || r.values[i] != raw[i].String
+		}
+
+		key := strings.Join(r.values, "\x00")
+		if _, ok := seen[key]; ok {
+			losers = append(losers, r.id)
+
+			continue
+		}
+
+		seen[key] = struct{}{}
+
+		if r.dirty {
+			winners = append(winners, r)
+		}
+	}
+
+	if err = rows.Close(); err != nil {
+		return fmt.Errorf("cannot read %s table keys: %w", table, err)
+	}
+
+	// NOTE(toby3d): duplicates must be deleted before keys rewriting,
+	// otherwise they violate the primary key.
+	for _, id := range losers {
+		if _, err = tx.Exec(`DELETE FROM `+table+` WHERE rowid = ?;`, id); err != nil {
+			return fmt.Errorf("cannot delete %s table duplicate: %w", table, err)
+		}
+	}
+
+	set := make([]string, len(keys))
+	for i := range keys {
+		set[i] = keys[i] + ` = ?`
+	}
+
+	for _, r := range winners {
+		args := make([]any, 0, len(r.values)+1)
+		for i := range r.values {
+			args = append(args, r.values[i])
+		}
+
+		if _, err = tx.Exec(`UPDATE `+table+` SET `+strings.Join(set, ", ")+` WHERE rowid = ?;`,
+			append(args, r.id)...); err != nil {
+			return fmt.Errorf("cannot normalize %s table keys: %w", table, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package sqlutil_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	t.Parallel()
+
+	db := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.MustExec(`CREATE TABLE topics (url TEXT PRIMARY KEY, updated_at INTEGER);
+		INSERT INTO topics (url, updated_at) VALUES ('a', 1), ('A', 3), ('b', 2), ('B', 1);`)
+
+	for i := 0; i < 2; i++ {
+		if err := sqlutil.Migrate(db, "lowercase", func(tx *sqlx.Tx) error {
+			if i > 0 {
+				t.Error("migration must be applied only once")
+			}
+
+			return sqlutil.MergeDuplicates(tx, "topics", "updated_at DESC", strings.ToLower, "url")
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	actual := make(map[string]int)
+
+	rows, err := db.Queryx(`SELECT url, updated_at FROM topics;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			u  string
+			ts int
+		)
+
+		if err = rows.Scan(&u, &ts); err != nil {
+			t.Fatal(err)
+		}
+
+		actual[u] = ts
+	}
+
+	if len(actual) != 2 || actual["a"] != 3 || actual["b"] != 2 {
+		t.Errorf("want only the latest rows of duplicates, got %v", actual)
+	}
+}
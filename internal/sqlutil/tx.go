@@ -0,0 +1,64 @@
+package sqlutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Transactor runs functions in a single database transaction which is shared
+// by every SQLite repository through the context.
+type Transactor struct {
+	db *sqlx.DB
+}
+
+type txKey struct{}
+
+func NewTransactor(db *sqlx.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// Transaction calls fn with a context which carries a new transaction. The
+// transaction is committed if fn returns nil, or rolled back otherwise. Nested
+// calls reuse the transaction of the outer one.
+func (t *Transactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("cannot begin transaction: %w", err)
+	}
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Stmt returns stmt bound to the transaction carried by ctx, if any.
+func Stmt(ctx context.Context, stmt *sqlx.Stmt) *sqlx.Stmt {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx.StmtxContext(ctx, stmt)
+	}
+
+	return stmt
+}
+
+// NamedStmt returns stmt bound to the transaction carried by ctx, if any.
+func NamedStmt(ctx context.Context, stmt *sqlx.NamedStmt) *sqlx.NamedStmt {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx.NamedStmtContext(ctx, stmt)
+	}
+
+	return stmt
+}
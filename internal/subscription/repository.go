@@ -3,6 +3,8 @@ package subscription
 import (
 	"context"
 	"errors"
+	"net/url"
+	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
 )
@@ -14,8 +16,30 @@ type (
 		Create(ctx context.Context, suid domain.SUID, subscription domain.Subscription) error
 		Get(ctx context.Context, suid domain.SUID) (*domain.Subscription, error)
 		Fetch(ctx context.Context, topic *domain.Topic) ([]domain.Subscription, error)
+		// FetchUnsynced returns topic subscriptions which are synced
+		// with older topic content version.
+		FetchUnsynced(ctx context.Context, topic domain.Topic) ([]domain.Subscription, error)
+		// FetchExpired returns subscriptions which are expired before ts.
+		FetchExpired(ctx context.Context, ts time.Time) ([]domain.Subscription, error)
 		Update(ctx context.Context, suid domain.SUID, update UpdateFunc) error
 		Delete(ctx context.Context, suid domain.SUID) (bool, error)
+		// Move re-keys all subscriptions of the from topic to the to
+		// topic, replacing the existing ones.
+		Move(ctx context.Context, from, to *url.URL) error
+	}
+
+	// DeliveryRepository is a log of content distribution attempts.
+	DeliveryRepository interface {
+		Create(ctx context.Context, delivery domain.Delivery) error
+		// FetchBySUID returns deliveries to subscription, the latest
+		// first.
+		FetchBySUID(ctx context.Context, suid domain.SUID) ([]domain.Delivery, error)
+		// FetchByTopic returns deliveries of topic to all it's
+		// subscribers, the latest first.
+		FetchByTopic(ctx context.Context, topic *url.URL) ([]domain.Delivery, error)
+		// Prune deletes deliveries created before ts and returns their
+		// number.
+		Prune(ctx context.Context, ts time.Time) (int, error)
 	}
 )
 
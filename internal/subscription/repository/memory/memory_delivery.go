@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/subscription"
+)
+
+type memoryDeliveryRepository struct {
+	mutex      *sync.RWMutex
+	deliveries []domain.Delivery
+}
+
+func NewMemoryDeliveryRepository() subscription.DeliveryRepository {
+	return &memoryDeliveryRepository{
+		mutex:      new(sync.RWMutex),
+		deliveries: make([]domain.Delivery, 0),
+	}
+}
+
+func (repo *memoryDeliveryRepository) Create(_ context.Context, d domain.Delivery) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	repo.deliveries = append(repo.deliveries, d)
+
+	return nil
+}
+
+func (repo *memoryDeliveryRepository) FetchBySUID(_ context.Context, suid domain.SUID) ([]domain.Delivery, error) {
+	return repo.fetch(func(d domain.Delivery) bool {
+		return sameURL(d.Topic, suid.Topic()) && sameURL(d.Callback, suid.Callback())
+	}), nil
+}
+
+func (repo *memoryDeliveryRepository) FetchByTopic(_ context.Context, u *url.URL) ([]domain.Delivery, error) {
+	return repo.fetch(func(d domain.Delivery) bool {
+		return sameURL(d.Topic, u)
+	}), nil
+}
+
+func (repo *memoryDeliveryRepository) Prune(_ context.Context, ts time.Time) (int, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	out := repo.deliveries[:0]
+
+	for _, d := range repo.deliveries {
+		if d.CreatedAt.Before(ts) {
+			continue
+		}
+
+		out = append(out, d)
+	}
+
+	count := len(repo.deliveries) - len(out)
+	repo.deliveries = out
+
+	return count, nil
+}
+
+func (repo *memoryDeliveryRepository) fetch(match func(domain.Delivery) bool) []domain.Delivery {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Delivery, 0)
+
+	for _, d := range repo.deliveries {
+		if match(d) {
+			out = append(out, d)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+
+	return out
+}
@@ -4,21 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"sync"
+	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
 	"source.toby3d.me/toby3d/hub/internal/subscription"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
 )
 
 type memorySubscriptionRepository struct {
 	mutex         *sync.RWMutex
-	subscriptions map[domain.SUID]domain.Subscription
+	subscriptions map[string]domain.Subscription
 }
 
 func NewMemorySubscriptionRepository() subscription.Repository {
 	return &memorySubscriptionRepository{
 		mutex:         new(sync.RWMutex),
-		subscriptions: make(map[domain.SUID]domain.Subscription),
+		subscriptions: make(map[string]domain.Subscription),
 	}
 }
 
@@ -34,7 +37,7 @@ func (repo *memorySubscriptionRepository) Create(ctx context.Context, suid domai
 	repo.mutex.Lock()
 	defer repo.mutex.Unlock()
 
-	repo.subscriptions[suid] = s
+	repo.subscriptions[key(suid)] = s
 
 	return nil
 }
@@ -51,7 +54,7 @@ func (repo *memorySubscriptionRepository) Delete(ctx context.Context, suid domai
 	repo.mutex.Lock()
 	defer repo.mutex.Unlock()
 
-	delete(repo.subscriptions, suid)
+	delete(repo.subscriptions, key(suid))
 
 	return true, nil
 }
@@ -60,7 +63,7 @@ func (repo *memorySubscriptionRepository) Get(_ context.Context, suid domain.SUI
 	repo.mutex.RLock()
 	defer repo.mutex.RUnlock()
 
-	if out, ok := repo.subscriptions[suid]; ok {
+	if out, ok := repo.subscriptions[key(suid)]; ok {
 		return &out, nil
 	}
 
@@ -74,7 +77,7 @@ func (repo *memorySubscriptionRepository) Fetch(ctx context.Context, t *domain.T
 	out := make([]domain.Subscription, 0)
 
 	for _, s := range repo.subscriptions {
-		if t != nil && t.Self.String() != s.Topic.String() {
+		if t != nil && !sameURL(t.Self, s.Topic) {
 			continue
 		}
 
@@ -84,22 +87,89 @@ func (repo *memorySubscriptionRepository) Fetch(ctx context.Context, t *domain.T
 	return out, nil
 }
 
-// Update implements subscription.Repository
-func (repo *memorySubscriptionRepository) Update(ctx context.Context, suid domain.SUID, update subscription.UpdateFunc) error {
-	in, err := repo.Get(ctx, suid)
-	if err != nil {
-		return fmt.Errorf("cannot update subscription: %w", err)
+func (repo *memorySubscriptionRepository) FetchUnsynced(_ context.Context, t domain.Topic) ([]domain.Subscription,
+	error,
+) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Subscription, 0)
+
+	for _, s := range repo.subscriptions {
+		if !sameURL(t.Self, s.Topic) || s.Synced(t) {
+			continue
+		}
+
+		out = append(out, s)
+	}
+
+	return out, nil
+}
+
+func (repo *memorySubscriptionRepository) FetchExpired(_ context.Context, ts time.Time) ([]domain.Subscription,
+	error,
+) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Subscription, 0)
+
+	for _, s := range repo.subscriptions {
+		if !s.Expired(ts) {
+			continue
+		}
+
+		out = append(out, s)
 	}
 
+	return out, nil
+}
+
+// Update implements subscription.Repository
+func (repo *memorySubscriptionRepository) Update(_ context.Context, suid domain.SUID, update subscription.UpdateFunc) error {
+	// NOTE(toby3d): subscription is read and written under the same lock,
+	// so concurrent updates never overwrite each other.
 	repo.mutex.Lock()
 	defer repo.mutex.Unlock()
 
-	out, err := update(in)
+	in, ok := repo.subscriptions[key(suid)]
+	if !ok {
+		return fmt.Errorf("cannot update subscription: %w", subscription.ErrNotExist)
+	}
+
+	out, err := update(&in)
 	if err != nil {
 		return fmt.Errorf("cannot update subscription: %w", err)
 	}
 
-	repo.subscriptions[suid] = *out
+	repo.subscriptions[key(suid)] = *out
 
 	return nil
 }
+
+func key(suid domain.SUID) string {
+	return urlutil.Canonical(suid.Topic()).String() + " " + urlutil.Canonical(suid.Callback()).String()
+}
+
+func (repo *memorySubscriptionRepository) Move(_ context.Context, from, to *url.URL) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for k, s := range repo.subscriptions {
+		if !sameURL(s.Topic, from) {
+			continue
+		}
+
+		delete(repo.subscriptions, k)
+
+		s.Topic = to
+		repo.subscriptions[key(s.SUID())] = s
+	}
+
+	return nil
+}
+
+// sameURL reports whether a and b are equivalent URLs.
+func sameURL(a, b *url.URL) bool {
+	return urlutil.Canonical(a).String() == urlutil.Canonical(b).String()
+}
@@ -0,0 +1,195 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+	"source.toby3d.me/toby3d/hub/internal/subscription"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+type (
+	Delivery struct {
+		CreatedAt DateTime `db:"created_at"`
+		Version   DateTime `db:"version"`
+		VersionID uint64   `db:"version_id"`
+		Topic     URL      `db:"topic"`
+		Callback  URL      `db:"callback"`
+		Body      string   `db:"body"`
+		Error     string   `db:"error"`
+		// Latency in milliseconds
+		Latency int64 `db:"latency"`
+		Attempt uint  `db:"attempt"`
+		Status  int   `db:"status"`
+	}
+
+	sqliteDeliveryRepository struct {
+		create       *sqlx.NamedStmt
+		fetchBySUID  *sqlx.Stmt
+		fetchByTopic *sqlx.Stmt
+		prune        *sqlx.Stmt
+	}
+)
+
+const (
+	tableDeliveries      string = "deliveries"
+	queryDeliveriesTable string = `CREATE TABLE IF NOT EXISTS ` + tableDeliveries + ` (
+		created_at DATETIME,
+		version DATETIME,
+		topic TEXT,
+		callback TEXT,
+		body TEXT DEFAULT '',
+		error TEXT DEFAULT '',
+		latency INTEGER DEFAULT 0,
+		attempt INTEGER DEFAULT 0,
+		status INTEGER DEFAULT 0,
+		version_id INTEGER DEFAULT 0
+	)`
+	queryDeliveriesIndex string = `CREATE INDEX IF NOT EXISTS idx_delivery ON ` + tableDeliveries +
+		` (topic, callback, created_at);
+		CREATE INDEX IF NOT EXISTS idx_delivery_created ON ` + tableDeliveries + ` (created_at);`
+	queryDeliveriesCreate string = `INSERT INTO ` + tableDeliveries + ` (created_at, version, topic, callback, ` +
+		`body, error, latency, attempt, status, version_id)
+		VALUES (:created_at, :version, :topic, :callback, :body, :error, :latency, :attempt, :status, ` +
+		`:version_id);`
+	queryDeliveriesFetchBySUID string = `SELECT * FROM ` + tableDeliveries + ` WHERE topic = ? AND callback = ?
+		ORDER BY created_at DESC, rowid DESC;`
+	queryDeliveriesFetchByTopic string = `SELECT * FROM ` + tableDeliveries + ` WHERE topic = ?
+		ORDER BY created_at DESC, rowid DESC;`
+	queryDeliveriesPrune string = `DELETE FROM ` + tableDeliveries + ` WHERE created_at < ?;`
+)
+
+func NewSQLiteDeliveryRepository(db *sqlx.DB) (subscription.DeliveryRepository, error) {
+	out := new(sqliteDeliveryRepository)
+
+	var err error
+	if _, err = db.Exec(queryDeliveriesTable); err != nil {
+		return nil, fmt.Errorf("delivery: sqlite: cannot prepare table: %w", err)
+	}
+
+	if err = sqlutil.AddColumns(db, tableDeliveries,
+		sqlutil.Column{Name: "version_id", Definition: "INTEGER DEFAULT 0"},
+	); err != nil {
+		return nil, fmt.Errorf("delivery: sqlite: cannot migrate table: %w", err)
+	}
+
+	if out.create, err = db.PrepareNamed(queryDeliveriesCreate); err != nil {
+		return nil, fmt.Errorf("delivery: sqlite: cannot create prepared named delivery statement: %w", err)
+	}
+
+	for q, dst := range map[string]**sqlx.Stmt{
+		queryDeliveriesFetchBySUID:  &out.fetchBySUID,
+		queryDeliveriesFetchByTopic: &out.fetchByTopic,
+		queryDeliveriesPrune:        &out.prune,
+	} {
+		if *dst, err = db.Preparex(q); err != nil {
+			return nil, fmt.Errorf("delivery: sqlite: cannot create prepared delivery statement: %w", err)
+		}
+	}
+
+	if _, err = db.Exec(queryDeliveriesIndex); err != nil {
+		return nil, fmt.Errorf("delivery: sqlite: cannot create index: %w", err)
+	}
+
+	return out, nil
+}
+
+func (repo *sqliteDeliveryRepository) Create(ctx context.Context, d domain.Delivery) error {
+	row := new(Delivery)
+	row.bind(d)
+
+	if _, err := repo.create.ExecContext(ctx, row); err != nil {
+		return fmt.Errorf("delivery: sqlite: cannot create delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *sqliteDeliveryRepository) FetchBySUID(ctx context.Context, suid domain.SUID) ([]domain.Delivery, error) {
+	rows, err := repo.fetchBySUID.QueryxContext(ctx, urlutil.Canonical(suid.Topic()).String(),
+		urlutil.Canonical(suid.Callback()).String())
+	if err != nil {
+		return nil, fmt.Errorf("delivery: sqlite: cannot fetch subscription deliveries: %w", err)
+	}
+
+	return scanDeliveries(rows)
+}
+
+func (repo *sqliteDeliveryRepository) FetchByTopic(ctx context.Context, u *url.URL) ([]domain.Delivery, error) {
+	rows, err := repo.fetchByTopic.QueryxContext(ctx, urlutil.Canonical(u).String())
+	if err != nil {
+		return nil, fmt.Errorf("delivery: sqlite: cannot fetch topic deliveries: %w", err)
+	}
+
+	return scanDeliveries(rows)
+}
+
+func (repo *sqliteDeliveryRepository) Prune(ctx context.Context, ts time.Time) (int, error) {
+	result, err := repo.prune.ExecContext(ctx, NewDateTime(ts))
+	if err != nil {
+		return 0, fmt.Errorf("delivery: sqlite: cannot prune deliveries: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delivery: sqlite: cannot read affected pruned rows result: %w", err)
+	}
+
+	return int(count), nil
+}
+
+func scanDeliveries(rows *sqlx.Rows) ([]domain.Delivery, error) {
+	defer rows.Close()
+
+	out := make([]domain.Delivery, 0)
+
+	for rows.Next() {
+		row := new(Delivery)
+		if err := rows.StructScan(row); err != nil {
+			return nil, fmt.Errorf("delivery: sqlite: cannot scan deliveries row: %w", err)
+		}
+
+		var d domain.Delivery
+		row.populate(&d)
+
+		out = append(out, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("delivery: sqlite: cannot read deliveries rows: %w", err)
+	}
+
+	return out, nil
+}
+
+func (d *Delivery) bind(src domain.Delivery) {
+	d.CreatedAt = NewDateTime(src.CreatedAt)
+	d.Version = NewDateTime(src.Version)
+	d.VersionID = src.VersionID
+	d.Topic = NewURL(src.Topic)
+	d.Callback = NewURL(src.Callback)
+	d.Body = src.Body
+	d.Error = src.Error
+	d.Latency = src.Latency.Milliseconds()
+	d.Attempt = src.Attempt
+	d.Status = src.Status
+}
+
+func (d Delivery) populate(dst *domain.Delivery) {
+	dst.CreatedAt = d.CreatedAt.DateTime
+	dst.Version = d.Version.DateTime
+	dst.VersionID = d.VersionID
+	dst.Topic = d.Topic.URL
+	dst.Callback = d.Callback.URL
+	dst.Body = d.Body
+	dst.Error = d.Error
+	dst.Latency = time.Duration(d.Latency) * time.Millisecond
+	dst.Attempt = d.Attempt
+	dst.Status = d.Status
+}
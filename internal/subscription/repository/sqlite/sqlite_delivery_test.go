@@ -0,0 +1,82 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	repository "source.toby3d.me/toby3d/hub/internal/subscription/repository/sqlite"
+)
+
+func TestDelivery(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLiteDeliveryRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	older := domain.TestDelivery(t)
+	older.CreatedAt = older.CreatedAt.Add(-48 * time.Hour)
+	older.Error = "subscriber replied with a non 2xx status: 503"
+	older.Status = 503
+
+	in := domain.TestDelivery(t)
+	in.Attempt = 2
+
+	other := domain.TestDelivery(t)
+	other.Callback = other.Callback.JoinPath("other")
+
+	// NOTE(toby3d): Create test.
+	for _, d := range []*domain.Delivery{older, in, other} {
+		if err = repo.Create(context.Background(), *d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// NOTE(toby3d): FetchBySUID test depends from Create.
+	actual, err := repo.FetchBySUID(context.Background(), in.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, []domain.Delivery{*in, *older}); diff != "" {
+		t.Error(diff)
+	}
+
+	// NOTE(toby3d): FetchByTopic test depends from Create.
+	if actual, err = repo.FetchByTopic(context.Background(), in.Topic); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actual) != 3 {
+		t.Errorf("want %d topic deliveries, got %d", 3, len(actual))
+	}
+
+	// NOTE(toby3d): Prune test depends from Create.
+	count, err := repo.Prune(context.Background(), in.CreatedAt.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Errorf("want %d pruned deliveries, got %d", 1, count)
+	}
+
+	if actual, err = repo.FetchBySUID(context.Background(), in.SUID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actual) != 1 {
+		t.Errorf("want %d subscription deliveries, got %d", 1, len(actual))
+	}
+}
@@ -12,18 +12,29 @@ import (
 	"github.com/jmoiron/sqlx"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
 	"source.toby3d.me/toby3d/hub/internal/subscription"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
 )
 
 type (
 	Subscription struct {
-		CreatedAt DateTime `db:"created_at"`
-		UpdatedAt DateTime `db:"updated_at"`
-		SyncedAt  DateTime `db:"synced_at"`
-		DeleteAt  DateTime `db:"delete_at"`
-		Topic     URL      `db:"topic"`
-		Callback  URL      `db:"callback"`
-		Secret    Secret   `db:"secret"`
+		CreatedAt    DateTime `db:"created_at"`
+		UpdatedAt    DateTime `db:"updated_at"`
+		SyncedAt     DateTime `db:"synced_at"`
+		SyncedID     uint64   `db:"synced_version_id"`
+		DeleteAt     DateTime `db:"delete_at"`
+		ReverifiedAt DateTime `db:"reverified_at"`
+		RenewedAt    DateTime `db:"renewed_at"`
+		PausedUntil  DateTime `db:"paused_until"`
+		SuspendedAt  DateTime `db:"suspended_at"`
+		ProbeAt      DateTime `db:"probe_at"`
+		Topic        URL      `db:"topic"`
+		Callback     URL      `db:"callback"`
+		Secret       Secret   `db:"secret"`
+		Full         bool     `db:"full"`
+		Circuit      Circuit  `db:"circuit"`
+		Failures     uint     `db:"failures"`
 	}
 
 	DateTime struct {
@@ -41,12 +52,21 @@ type (
 		Valid  bool
 	}
 
+	Circuit struct {
+		Circuit domain.CircuitState
+		Valid   bool
+	}
+
 	sqliteSubscriptionRepository struct {
-		create *sqlx.NamedStmt
-		update *sqlx.NamedStmt
-		read   *sqlx.Stmt
-		fetch  *sqlx.Stmt
-		delete *sqlx.Stmt
+		transactor    *sqlutil.Transactor
+		create        *sqlx.NamedStmt
+		update        *sqlx.NamedStmt
+		read          *sqlx.Stmt
+		fetch         *sqlx.Stmt
+		fetchUnsynced *sqlx.Stmt
+		fetchExpired  *sqlx.Stmt
+		delete        *sqlx.Stmt
+		move          *sqlx.Stmt
 	}
 )
 
@@ -60,31 +80,80 @@ const (
 		topic TEXT,
 		callback TEXT,
 		secret TEXT,
+		full INTEGER DEFAULT 0,
+		reverified_at DATETIME DEFAULT 0,
+		renewed_at DATETIME DEFAULT 0,
+		paused_until DATETIME DEFAULT 0,
+		suspended_at DATETIME DEFAULT 0,
+		probe_at DATETIME DEFAULT 0,
+		circuit TEXT DEFAULT 'closed',
+		failures INTEGER DEFAULT 0,
+		synced_version_id INTEGER DEFAULT 0,
 		PRIMARY KEY (topic, callback)
 	)`
-	queryIndex  string = `CREATE INDEX IF NOT EXISTS idx_subscription ON ` + table + ` (topic, callback);`
-	queryCreate string = `INSERT INTO ` + table + ` (created_at, updated_at, synced_at, delete_at, topic, ` +
-		`callback, secret)
-		VALUES (:created_at, :updated_at, :synced_at, :delete_at, :topic, :callback, :secret);`
-	queryFetch  string = `SELECT * FROM ` + table + ` WHERE topic = ?;`
-	queryRead   string = `SELECT * FROM ` + table + ` WHERE topic = ? AND callback = ?;`
-	queryUpdate string = `UPDATE ` + table + `
+	queryIndex string = `CREATE INDEX IF NOT EXISTS idx_subscription ON ` + table + ` (topic, callback);
+		CREATE INDEX IF NOT EXISTS idx_subscription_synced ON ` + table + ` (topic, synced_version_id);
+		CREATE INDEX IF NOT EXISTS idx_subscription_delete ON ` + table + ` (delete_at);`
+	queryCreate string = `INSERT INTO ` + table + ` (created_at, updated_at, synced_at, delete_at, ` +
+		`reverified_at, renewed_at, paused_until, suspended_at, probe_at, topic, callback, secret, full, ` +
+		`circuit, failures, synced_version_id)
+		VALUES (:created_at, :updated_at, :synced_at, :delete_at, :reverified_at, :renewed_at, ` +
+		`:paused_until, :suspended_at, :probe_at, :topic, :callback, :secret, :full, :circuit, :failures, ` +
+		`:synced_version_id);`
+	queryFetch         string = `SELECT * FROM ` + table + ` WHERE topic = ?;`
+	queryFetchUnsynced string = `SELECT * FROM ` + table + ` WHERE topic = ? AND synced_version_id < ?;`
+	queryFetchExpired  string = `SELECT * FROM ` + table + ` WHERE delete_at < ?;`
+	queryRead          string = `SELECT * FROM ` + table + ` WHERE topic = ? AND callback = ?;`
+	queryUpdate        string = `UPDATE ` + table + `
 				SET updated_at = :updated_at,
 					synced_at = :synced_at,
+					synced_version_id = :synced_version_id,
 					delete_at = :delete_at,
-					secret = :secret
-				WHERE topic = ? AND callback = ?;`
+					reverified_at = :reverified_at,
+					renewed_at = :renewed_at,
+					paused_until = :paused_until,
+					suspended_at = :suspended_at,
+					probe_at = :probe_at,
+					circuit = :circuit,
+					failures = :failures,
+					secret = :secret,
+					full = :full
+				WHERE topic = :topic AND callback = :callback;`
 	queryDelete string = `DELETE FROM ` + table + ` WHERE topic = ? AND callback = ?;`
+	queryMove   string = `UPDATE OR REPLACE ` + table + ` SET topic = ? WHERE topic = ?;`
 )
 
 func NewSQLiteSubscriptionRepository(db *sqlx.DB) (subscription.Repository, error) {
-	out := new(sqliteSubscriptionRepository)
+	out := &sqliteSubscriptionRepository{transactor: sqlutil.NewTransactor(db)}
 
 	var err error
 	if _, err = db.Exec(queryTable); err != nil {
 		return nil, fmt.Errorf("subscription: sqlite: cannot prepare table: %w", err)
 	}
 
+	if err = sqlutil.AddColumns(db, table,
+		sqlutil.Column{Name: "full", Definition: "INTEGER DEFAULT 0"},
+		sqlutil.Column{Name: "reverified_at", Definition: "DATETIME DEFAULT 0"},
+		sqlutil.Column{Name: "renewed_at", Definition: "DATETIME DEFAULT 0"},
+		sqlutil.Column{Name: "paused_until", Definition: "DATETIME DEFAULT 0"},
+		sqlutil.Column{Name: "suspended_at", Definition: "DATETIME DEFAULT 0"},
+		sqlutil.Column{Name: "probe_at", Definition: "DATETIME DEFAULT 0"},
+		sqlutil.Column{Name: "circuit", Definition: "TEXT DEFAULT 'closed'"},
+		sqlutil.Column{Name: "failures", Definition: "INTEGER DEFAULT 0"},
+		sqlutil.Column{Name: "synced_version_id", Definition: "INTEGER DEFAULT 0"},
+	); err != nil {
+		return nil, fmt.Errorf("subscription: sqlite: cannot migrate table: %w", err)
+	}
+
+	// NOTE(toby3d): previous versions of the hub stored URLs as is, so
+	// equivalent URLs of the same resource may be stored in separate rows.
+	if err = sqlutil.Migrate(db, table+"_canonical_urls", func(tx *sqlx.Tx) error {
+		return sqlutil.MergeDuplicates(tx, table, "updated_at DESC", urlutil.CanonicalString, "topic",
+			"callback")
+	}); err != nil {
+		return nil, fmt.Errorf("subscription: sqlite: cannot migrate table: %w", err)
+	}
+
 	for q, dst := range map[string]**sqlx.NamedStmt{
 		queryCreate: &out.create,
 		queryUpdate: &out.update,
@@ -96,9 +165,12 @@ func NewSQLiteSubscriptionRepository(db *sqlx.DB) (subscription.Repository, erro
 	}
 
 	for q, dst := range map[string]**sqlx.Stmt{
-		queryDelete: &out.delete,
-		queryFetch:  &out.fetch,
-		queryRead:   &out.read,
+		queryDelete:        &out.delete,
+		queryMove:          &out.move,
+		queryFetch:         &out.fetch,
+		queryFetchUnsynced: &out.fetchUnsynced,
+		queryFetchExpired:  &out.fetchExpired,
+		queryRead:          &out.read,
 	} {
 		if *dst, err = db.Preparex(q); err != nil {
 			return nil, fmt.Errorf("subscription: sqlite: cannot create prepared subscription statement: "+
@@ -114,10 +186,16 @@ func NewSQLiteSubscriptionRepository(db *sqlx.DB) (subscription.Repository, erro
 }
 
 func (repo *sqliteSubscriptionRepository) Create(ctx context.Context, id domain.SUID, s domain.Subscription) error {
+	if _, err := repo.Get(ctx, id); err == nil {
+		return fmt.Errorf("subscription: sqlite: cannot create subscription: %w", subscription.ErrExist)
+	} else if !errors.Is(err, subscription.ErrNotExist) {
+		return fmt.Errorf("subscription: sqlite: cannot check subscription: %w", err)
+	}
+
 	row := new(Subscription)
 	row.bind(s)
 
-	if _, err := repo.create.ExecContext(ctx, row); err != nil {
+	if _, err := sqlutil.NamedStmt(ctx, repo.create).ExecContext(ctx, row); err != nil {
 		return fmt.Errorf("subscription: sqlite: cannot create subscription: %w", err)
 	}
 
@@ -126,28 +204,61 @@ func (repo *sqliteSubscriptionRepository) Create(ctx context.Context, id domain.
 
 func (repo *sqliteSubscriptionRepository) Get(ctx context.Context, id domain.SUID) (*domain.Subscription, error) {
 	row := new(Subscription)
-	if err := repo.read.GetContext(ctx, row, id.Topic().String(), id.Callback().String()); err != nil {
+	if err := sqlutil.Stmt(ctx, repo.read).GetContext(ctx, row, urlutil.Canonical(id.Topic()).String(),
+		urlutil.Canonical(id.Callback()).String()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, subscription.ErrNotExist
+		}
+
 		return nil, fmt.Errorf("subscription: sqlite: cannot get subscription row: %w", err)
 	}
 
 	out := new(domain.Subscription)
 	row.populate(out)
 
-	return nil, nil
+	return out, nil
 }
 
 func (repo *sqliteSubscriptionRepository) Fetch(ctx context.Context, t *domain.Topic) ([]domain.Subscription, error) {
-	rows, err := repo.fetch.QueryxContext(ctx, t.Self.String())
+	rows, err := repo.fetch.QueryxContext(ctx, urlutil.Canonical(t.Self).String())
 	if err != nil {
 		return nil, fmt.Errorf("subscription: sqlite: cannot fetch subscription: %w", err)
 	}
+
+	return scan(rows)
+}
+
+func (repo *sqliteSubscriptionRepository) FetchUnsynced(ctx context.Context, t domain.Topic) ([]domain.Subscription,
+	error,
+) {
+	rows, err := repo.fetchUnsynced.QueryxContext(ctx, urlutil.Canonical(t.Self).String(),
+		t.VersionID)
+	if err != nil {
+		return nil, fmt.Errorf("subscription: sqlite: cannot fetch unsynced subscriptions: %w", err)
+	}
+
+	return scan(rows)
+}
+
+func (repo *sqliteSubscriptionRepository) FetchExpired(ctx context.Context, ts time.Time) ([]domain.Subscription,
+	error,
+) {
+	rows, err := repo.fetchExpired.QueryxContext(ctx, NewDateTime(ts))
+	if err != nil {
+		return nil, fmt.Errorf("subscription: sqlite: cannot fetch expired subscriptions: %w", err)
+	}
+
+	return scan(rows)
+}
+
+func scan(rows *sqlx.Rows) ([]domain.Subscription, error) {
 	defer rows.Close()
 
 	out := make([]domain.Subscription, 0)
 
 	for rows.Next() {
 		row := new(Subscription)
-		if err = rows.StructScan(row); err != nil {
+		if err := rows.StructScan(row); err != nil {
 			return nil, fmt.Errorf("subscription: sqlite: cannot scan subscriptions row: %w", err)
 		}
 
@@ -157,32 +268,50 @@ func (repo *sqliteSubscriptionRepository) Fetch(ctx context.Context, t *domain.T
 		out = append(out, s)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("subscription: sqlite: cannot read subscriptions rows: %w", err)
+	}
+
 	return out, nil
 }
 
 func (repo *sqliteSubscriptionRepository) Update(ctx context.Context, id domain.SUID, update subscription.UpdateFunc) error {
-	in, err := repo.Get(ctx, id)
-	if err != nil {
-		return fmt.Errorf("subscription: sqlite: cannot find updating subscription: %w", err)
-	}
+	// NOTE(toby3d): subscription is read and written in the same
+	// transaction, so concurrent updates never overwrite each other.
+	return repo.transactor.Transaction(ctx, func(ctx context.Context) error {
+		in, err := repo.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("subscription: sqlite: cannot find updating subscription: %w", err)
+		}
 
-	out, err := update(in)
-	if err != nil {
-		return fmt.Errorf("subscription: sqlite: cannot update subscription: %w", err)
-	}
+		out, err := update(in)
+		if err != nil {
+			return fmt.Errorf("subscription: sqlite: cannot update subscription: %w", err)
+		}
 
-	row := new(Subscription)
-	row.bind(*out)
+		row := new(Subscription)
+		row.bind(*out)
 
-	if _, err = repo.update.ExecContext(ctx, row); err != nil {
-		return fmt.Errorf("subscription: sqlite: cannot update subscription row: %w", err)
+		if _, err = sqlutil.NamedStmt(ctx, repo.update).ExecContext(ctx, row); err != nil {
+			return fmt.Errorf("subscription: sqlite: cannot update subscription row: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (repo *sqliteSubscriptionRepository) Move(ctx context.Context, from, to *url.URL) error {
+	if _, err := sqlutil.Stmt(ctx, repo.move).ExecContext(ctx, urlutil.Canonical(to).String(),
+		urlutil.Canonical(from).String()); err != nil {
+		return fmt.Errorf("subscription: sqlite: cannot move subscriptions: %w", err)
 	}
 
 	return nil
 }
 
 func (repo *sqliteSubscriptionRepository) Delete(ctx context.Context, id domain.SUID) (bool, error) {
-	result, err := repo.delete.ExecContext(ctx, id.Topic().String(), id.Callback().String())
+	result, err := sqlutil.Stmt(ctx, repo.delete).ExecContext(ctx, urlutil.Canonical(id.Topic()).String(),
+		urlutil.Canonical(id.Callback()).String())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
@@ -203,10 +332,19 @@ func (s *Subscription) bind(src domain.Subscription) {
 	s.CreatedAt = NewDateTime(src.CreatedAt)
 	s.UpdatedAt = NewDateTime(src.UpdatedAt)
 	s.SyncedAt = NewDateTime(src.SyncedAt)
+	s.SyncedID = src.SyncedVersionID
 	s.DeleteAt = NewDateTime(src.ExpiredAt)
+	s.ReverifiedAt = NewDateTime(src.ReverifiedAt)
+	s.RenewedAt = NewDateTime(src.RenewedAt)
+	s.PausedUntil = NewDateTime(src.PausedUntil)
+	s.SuspendedAt = NewDateTime(src.SuspendedAt)
+	s.ProbeAt = NewDateTime(src.ProbeAt)
 	s.Topic = NewURL(src.Topic)
 	s.Callback = NewURL(src.Callback)
 	s.Secret = NewSecret(src.Secret)
+	s.Full = src.Full
+	s.Circuit = NewCircuit(src.Circuit)
+	s.Failures = src.Failures
 }
 
 func (s Subscription) populate(dst *domain.Subscription) {
@@ -214,9 +352,18 @@ func (s Subscription) populate(dst *domain.Subscription) {
 	dst.UpdatedAt = s.UpdatedAt.DateTime
 	dst.ExpiredAt = s.DeleteAt.DateTime
 	dst.SyncedAt = s.SyncedAt.DateTime
+	dst.SyncedVersionID = s.SyncedID
+	dst.ReverifiedAt = s.ReverifiedAt.DateTime
+	dst.RenewedAt = s.RenewedAt.DateTime
+	dst.PausedUntil = s.PausedUntil.DateTime
+	dst.SuspendedAt = s.SuspendedAt.DateTime
+	dst.ProbeAt = s.ProbeAt.DateTime
 	dst.Callback = s.Callback.URL
 	dst.Topic = s.Topic.URL
 	dst.Secret = s.Secret.Secret
+	dst.Full = s.Full
+	dst.Circuit = s.Circuit.Circuit
+	dst.Failures = s.Failures
 }
 
 func NewURL(u *url.URL) URL {
@@ -252,7 +399,7 @@ func (u URL) Value() (driver.Value, error) {
 		return "", nil
 	}
 
-	return u.URL.String(), nil
+	return urlutil.Canonical(u.URL).String(), nil
 }
 
 func NewDateTime(t time.Time) DateTime {
@@ -265,6 +412,14 @@ func NewDateTime(t time.Time) DateTime {
 func (dt *DateTime) Scan(src any) error {
 	switch s := src.(type) {
 	case int64:
+		// NOTE(toby3d): zero time is stored as zero timestamp, see
+		// Value.
+		if s == 0 {
+			*dt = DateTime{}
+
+			return nil
+		}
+
 		dt.DateTime = time.Unix(s, 0)
 		dt.Valid = true
 	}
@@ -274,7 +429,7 @@ func (dt *DateTime) Scan(src any) error {
 
 func (dt DateTime) Value() (driver.Value, error) {
 	if !dt.Valid {
-		return 0, nil
+		return int64(0), nil
 	}
 
 	return dt.DateTime.Unix(), nil
@@ -316,3 +471,45 @@ func (s Secret) Value() (driver.Value, error) {
 
 	return s.Secret.String(), nil
 }
+
+func NewCircuit(state domain.CircuitState) Circuit {
+	return Circuit{
+		Circuit: state,
+		Valid:   state != domain.CircuitStateUnd,
+	}
+}
+
+func (c *Circuit) Scan(src any) error {
+	var value string
+
+	switch raw := src.(type) {
+	default:
+	case []byte:
+		value = string(raw)
+	case string:
+		value = raw
+	}
+
+	if value == "" {
+		*c = Circuit{}
+
+		return nil
+	}
+
+	var err error
+	if c.Circuit, err = domain.ParseCircuitState(value); err != nil {
+		return fmt.Errorf("Circuit: cannot scan value as CircuitState: %w", err)
+	}
+
+	c.Valid = true
+
+	return nil
+}
+
+func (c Circuit) Value() (driver.Value, error) {
+	if !c.Valid {
+		return domain.CircuitStateClosed.String(), nil
+	}
+
+	return c.Circuit.String(), nil
+}
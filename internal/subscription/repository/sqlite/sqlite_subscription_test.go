@@ -0,0 +1,246 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+	"source.toby3d.me/toby3d/hub/internal/subscription"
+	repository "source.toby3d.me/toby3d/hub/internal/subscription/repository/sqlite"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+func Test(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLiteSubscriptionRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := domain.TestSubscription(t, "https://example.net/callback")
+	in.SyncedAt = in.CreatedAt
+
+	// NOTE(toby3d): Create test.
+	if err = repo.Create(context.Background(), in.SUID(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE(toby3d): Get test depends from Create.
+	actual, err := repo.Get(context.Background(), in.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, in, cmp.AllowUnexported(domain.Secret{}, domain.CircuitState{})); diff != "" {
+		t.Error(diff)
+	}
+
+	// NOTE(toby3d): FetchUnsynced test depends from Create.
+	topic := domain.Topic{
+		Self:      in.Topic,
+		UpdatedAt: in.SyncedAt.Add(time.Minute),
+		VersionID: in.SyncedVersionID + 1,
+	}
+
+	unsynced, err := repo.FetchUnsynced(context.Background(), topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unsynced) != 1 {
+		t.Errorf("want %d unsynced subscriptions, got %d", 1, len(unsynced))
+	}
+
+	// NOTE(toby3d): Update test depends from Create.
+	if err = repo.Update(context.Background(), in.SUID(), func(tx *domain.Subscription) (*domain.Subscription,
+		error,
+	) {
+		tx.SyncedAt = topic.UpdatedAt
+		tx.SyncedVersionID = topic.VersionID
+		tx.ReverifiedAt = topic.UpdatedAt
+		tx.RenewedAt = topic.UpdatedAt
+		tx.PausedUntil = topic.UpdatedAt
+		tx.Circuit = domain.CircuitStateOpen
+		tx.Failures = 3
+
+		return tx, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, err = repo.Get(context.Background(), in.SUID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !actual.RenewedAt.Equal(topic.UpdatedAt) || !actual.ReverifiedAt.Equal(topic.UpdatedAt) ||
+		!actual.PausedUntil.Equal(topic.UpdatedAt) {
+		t.Errorf("want renewed at %s, got %s", topic.UpdatedAt, actual.RenewedAt)
+	}
+
+	if actual.Circuit != domain.CircuitStateOpen || actual.Failures != 3 {
+		t.Errorf("want %s circuit after %d failures, got %s after %d", domain.CircuitStateOpen, 3, actual.Circuit,
+			actual.Failures)
+	}
+
+	if unsynced, err = repo.FetchUnsynced(context.Background(), topic); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unsynced) != 0 {
+		t.Errorf("want %d unsynced subscriptions, got %d", 0, len(unsynced))
+	}
+
+	// NOTE(toby3d): FetchExpired test depends from Create.
+	expired, err := repo.FetchExpired(context.Background(), in.ExpiredAt.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(expired) != 1 {
+		t.Errorf("want %d expired subscriptions, got %d", 1, len(expired))
+	}
+
+	// NOTE(toby3d): Move test depends from Create.
+	moved := *in
+	moved.Topic = in.Topic.JoinPath("moved")
+
+	if err = sqlutil.NewTransactor(tdb).Transaction(context.Background(), func(ctx context.Context) error {
+		return repo.Move(ctx, in.Topic, moved.Topic)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = repo.Get(context.Background(), in.SUID()); !errors.Is(err, subscription.ErrNotExist) {
+		t.Errorf("want %v error, got %v", subscription.ErrNotExist, err)
+	}
+
+	if _, err = repo.Get(context.Background(), moved.SUID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = repo.Move(context.Background(), moved.Topic, in.Topic); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE(toby3d): Delete test depends from Create.
+	ok, err := repo.Delete(context.Background(), in.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Errorf("want %t, got %t", true, ok)
+	}
+
+	if _, err = repo.Get(context.Background(), in.SUID()); !errors.Is(err, subscription.ErrNotExist) {
+		t.Errorf("want %v error, got %v", subscription.ErrNotExist, err)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLiteSubscriptionRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := domain.TestSubscription(t, "https://Example.net:443/callback")
+	in.Topic, _ = url.Parse("https://Example.com:443/feed")
+
+	if err = repo.Create(context.Background(), in.SUID(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE(toby3d): equivalent URLs make the same subscription.
+	if err = repo.Create(context.Background(), in.SUID(), *in); !errors.Is(err, subscription.ErrExist) {
+		t.Errorf("want %v error, got %v", subscription.ErrExist, err)
+	}
+
+	for _, suid := range []domain.SUID{
+		in.SUID(),
+		domain.NewSSID(domain.Topic{Self: in.Topic}, in.Callback),
+		domain.NewSSID(domain.Topic{Self: urlutil.Canonical(in.Topic)}, urlutil.Canonical(in.Callback)),
+	} {
+		if _, err = repo.Get(context.Background(), suid); err != nil {
+			t.Errorf("%#v: %s", suid, err)
+		}
+	}
+
+	ok, err := repo.Delete(context.Background(), in.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok {
+		t.Errorf("want %t, got %t", true, ok)
+	}
+}
+
+func TestUpdate_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", sqlutil.DSN(filepath.Join(t.TempDir(), "testing.db")))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLiteSubscriptionRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := domain.TestSubscription(t, "https://example.net/callback")
+	if err = repo.Create(context.Background(), in.SUID(), *in); err != nil {
+		t.Fatal(err)
+	}
+
+	const updates int = 32
+
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := repo.Update(context.Background(), in.SUID(), func(tx *domain.Subscription) (
+				*domain.Subscription, error,
+			) {
+				tx.Failures++
+
+				return tx, nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	out, err := repo.Get(context.Background(), in.SUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE(toby3d): concurrent updates never overwrite each other.
+	if expect := in.Failures + uint(updates); out.Failures != expect {
+		t.Errorf("want %d failures, got %d", expect, out.Failures)
+	}
+}
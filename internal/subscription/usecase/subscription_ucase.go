@@ -6,65 +6,120 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/common"
+	"source.toby3d.me/toby3d/hub/internal/discovery"
 	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/feed"
 	"source.toby3d.me/toby3d/hub/internal/subscription"
 	"source.toby3d.me/toby3d/hub/internal/topic"
 )
 
-type subscriptionUseCase struct {
-	topics        topic.Repository
-	subscriptions subscription.Repository
-	client        *http.Client
-}
+type (
+	NewSubscriptionUseCaseParams struct {
+		Subscriptions subscription.Repository
+		Topics        topic.Repository
+		Client        *http.Client
+		// Hub is the URL of this hub which subscribed topics must
+		// advertise, if not nil. Only new topics are fetched for the
+		// check: every path which stores topic content checks it too,
+		// so known topics already advertise the hub by their stored
+		// content.
+		Hub *url.URL
+	}
+
+	subscriptionUseCase struct {
+		topics        topic.Repository
+		subscriptions subscription.Repository
+		client        *http.Client
+		hub           *url.URL
+	}
+)
 
-func NewSubscriptionUseCase(subs subscription.Repository, tops topic.Repository, c *http.Client) subscription.UseCase {
+func NewSubscriptionUseCase(params NewSubscriptionUseCaseParams) subscription.UseCase {
 	return &subscriptionUseCase{
-		subscriptions: subs,
-		topics:        tops,
-		client:        c,
+		subscriptions: params.Subscriptions,
+		topics:        params.Topics,
+		client:        params.Client,
+		hub:           params.Hub,
 	}
 }
 
 func (ucase *subscriptionUseCase) Subscribe(ctx context.Context, s domain.Subscription) (bool, error) {
 	now := time.Now().UTC().Round(time.Second)
+	// NOTE(toby3d): lease is granted since verification of intent, not since
+	// subscription request.
+	lease := time.Duration(s.LeaseSeconds()) * time.Second
 
-	if _, err := ucase.topics.Get(context.Background(), s.Topic); err != nil {
+	t, err := ucase.topics.Get(ctx, s.Topic)
+	if err != nil {
 		if !errors.Is(err, topic.ErrNotExist) {
 			return false, fmt.Errorf("cannot check subscription topic: %w", err)
 		}
 
-		resp, err := ucase.client.Get(s.Topic.String())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.Topic.String(), nil)
+		if err != nil {
+			return false, fmt.Errorf("cannot build a new topic subscription request: %w: %w",
+				domain.ErrReasonTopic, err)
+		}
+
+		resp, err := ucase.client.Do(req)
 		if err != nil {
-			return false, fmt.Errorf("cannot fetch a new topic subscription content: %w", err)
+			return false, fmt.Errorf("cannot fetch a new topic subscription content: %w: %w",
+				domain.ErrReasonTopic, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			return false, fmt.Errorf("cannot fetch a new topic subscription content: %w: status %d",
+				domain.ErrReasonTopic, resp.StatusCode)
 		}
 
 		content, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return false, fmt.Errorf("cannot read a new topic subscription content: %w", err)
+			return false, fmt.Errorf("cannot read a new topic subscription content: %w: %w",
+				domain.ErrReasonTopic, err)
 		}
 
-		if err = ucase.topics.Create(ctx, s.Topic, domain.Topic{
-			CreatedAt:   now,
-			UpdatedAt:   now,
-			Self:        s.Topic,
-			ContentType: resp.Header.Get(common.HeaderContentType),
-			Content:     content,
-		}); err != nil {
+		links := discovery.Discover(resp.Request.URL, resp.Header, content)
+		if ucase.hub != nil && !links.Advertises(ucase.hub) {
+			return false, fmt.Errorf("cannot subscribe: %w: %w", domain.ErrReasonTopic, topic.ErrHub)
+		}
+
+		t = &domain.Topic{
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			Self:         s.Topic,
+			ContentType:  resp.Header.Get(common.HeaderContentType),
+			Content:      content,
+			Canonical:    links.Self,
+			ETag:         resp.Header.Get(common.HeaderETag),
+			LastModified: resp.Header.Get(common.HeaderLastModified),
+			Hash:         domain.NewContentHash(content),
+			VersionID:    1,
+		}
+		t.Entries = feed.Record(*t, nil)
+
+		if err = ucase.topics.Create(ctx, s.Topic, *t); err != nil {
 			return false, fmt.Errorf("cannot create topic for subsciption: %w", err)
 		}
 	}
 
-	if err := ucase.subscriptions.Create(ctx, s.SUID(), domain.Subscription{
-		CreatedAt: now,
-		UpdatedAt: now,
-		SyncedAt:  now,
-		ExpiredAt: s.ExpiredAt,
-		Callback:  s.Callback,
-		Topic:     s.Topic,
-		Secret:    s.Secret,
+	// NOTE(toby3d): subscriber receives only content which will be
+	// published after subscription.
+	if err = ucase.subscriptions.Create(ctx, s.SUID(), domain.Subscription{
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		SyncedAt:        now,
+		SyncedVersionID: t.VersionID,
+		ExpiredAt:       now.Add(lease),
+		Callback:        s.Callback,
+		Topic:           s.Topic,
+		Secret:          s.Secret,
+		Full:            s.Full,
+		Circuit:         domain.CircuitStateClosed,
 	}); err != nil {
 		if !errors.Is(err, subscription.ErrExist) {
 			return false, fmt.Errorf("cannot create a new subscription: %w", err)
@@ -74,8 +129,9 @@ func (ucase *subscriptionUseCase) Subscribe(ctx context.Context, s domain.Subscr
 			error,
 		) {
 			tx.UpdatedAt = now
-			tx.ExpiredAt = now.Add(time.Duration(s.LeaseSeconds()) * time.Second)
+			tx.ExpiredAt = now.Add(lease)
 			tx.Secret = s.Secret
+			tx.Full = s.Full
 
 			return tx, nil
 		}); err != nil {
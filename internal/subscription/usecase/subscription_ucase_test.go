@@ -2,11 +2,14 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/common"
 	"source.toby3d.me/toby3d/hub/internal/domain"
@@ -35,7 +38,11 @@ func TestSubscriptionUseCase_Subscribe(t *testing.T) {
 	topics := topicmemoryrepo.NewMemoryTopicRepository()
 	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
 
-	ucase := usecase.NewSubscriptionUseCase(subscriptions, topics, callback.Client())
+	ucase := usecase.NewSubscriptionUseCase(usecase.NewSubscriptionUseCaseParams{
+		Subscriptions: subscriptions,
+		Topics:        topics,
+		Client:        callback.Client(),
+	})
 
 	ok, err := ucase.Subscribe(context.Background(), *subscription)
 	if err != nil {
@@ -64,6 +71,107 @@ func TestSubscriptionUseCase_Subscribe(t *testing.T) {
 	})
 }
 
+func TestSubscriptionUseCase_Subscribe_Strict(t *testing.T) {
+	t.Parallel()
+
+	hub := domain.TestConfig(t).BaseURL
+
+	for name, tc := range map[string]struct {
+		link   string
+		expect error
+		known  bool
+	}{
+		"advertised": {link: `<` + hub.String() + `>; rel="hub"`, expect: nil},
+		"missing":    {link: `<https://hub.example.net/>; rel="hub"`, expect: domain.ErrReasonTopic},
+		// NOTE(toby3d): known topic already passed the check when it's
+		// content was stored, so it is not fetched again.
+		"known": {link: `<https://hub.example.net/>; rel="hub"`, expect: nil, known: true},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var fetches int32
+
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				atomic.AddInt32(&fetches, 1)
+				w.Header().Set(common.HeaderLink, tc.link)
+				w.Header().Set(common.HeaderContentType, common.MIMETextPlainCharsetUTF8)
+				fmt.Fprint(w, "hello, world")
+			}))
+			t.Cleanup(srv.Close)
+
+			subscription := domain.TestSubscription(t, "https://example.com/callback")
+			subscription.Topic, _ = url.Parse(srv.URL + "/")
+
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+
+			if tc.known {
+				topic := domain.TestTopic(t)
+				topic.Self = subscription.Topic
+
+				if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := usecase.NewSubscriptionUseCase(usecase.NewSubscriptionUseCaseParams{
+				Subscriptions: subscriptionmemoryrepo.NewMemorySubscriptionRepository(),
+				Topics:        topics,
+				Client:        srv.Client(),
+				Hub:           hub,
+			}).Subscribe(context.Background(), *subscription)
+			if !errors.Is(err, tc.expect) {
+				t.Errorf("want %v error, got %v", tc.expect, err)
+			}
+
+			if tc.known && atomic.LoadInt32(&fetches) != 0 {
+				t.Errorf("want known topic not fetched, got %d fetches", fetches)
+			}
+		})
+	}
+}
+
+func TestSubscriptionUseCase_Subscribe_Canceled(t *testing.T) {
+	t.Parallel()
+
+	topic := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(topic.Close)
+
+	subscription := domain.TestSubscription(t, "https://example.net/")
+	subscription.Topic, _ = url.Parse(topic.URL + "/")
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	t.Cleanup(cancel)
+
+	start := time.Now()
+
+	// NOTE(toby3d): fetch of a slow new topic must be canceled with
+	// context instead of waiting for the client timeout.
+	if _, err := usecase.NewSubscriptionUseCase(usecase.NewSubscriptionUseCaseParams{
+		Subscriptions: subscriptions,
+		Topics:        topicmemoryrepo.NewMemoryTopicRepository(),
+		Client:        topic.Client(),
+	}).Subscribe(ctx, *subscription); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Subscribe() took %s, want less than %s", elapsed, time.Second)
+	}
+
+	if _, err := subscriptions.Get(context.Background(), subscription.SUID()); err == nil {
+		t.Error("subscription is created with canceled context")
+	}
+}
+
 func TestSubscriptionUseCase_Unsubscribe(t *testing.T) {
 	t.Parallel()
 
@@ -81,8 +189,11 @@ func TestSubscriptionUseCase_Unsubscribe(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	ok, err := usecase.NewSubscriptionUseCase(subscriptions, topics, srv.Client()).
-		Unsubscribe(context.Background(), *subscription)
+	ok, err := usecase.NewSubscriptionUseCase(usecase.NewSubscriptionUseCaseParams{
+		Subscriptions: subscriptions,
+		Topics:        topics,
+		Client:        srv.Client(),
+	}).Unsubscribe(context.Background(), *subscription)
 	if err != nil {
 		t.Fatal(err)
 	}
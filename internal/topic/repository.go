@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/url"
+	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
 )
@@ -14,15 +15,55 @@ type (
 	Repository interface {
 		Create(ctx context.Context, u *url.URL, topic domain.Topic) error
 		Update(ctx context.Context, u *url.URL, update UpdateFunc) error
-		// TODO(toby3d): search by URL prefix for publish every topic on
-		// domain or it's directory.
+		// Reschedule sets only the next polling time of topic, so it
+		// never overwrites concurrently updated content.
+		Reschedule(ctx context.Context, u *url.URL, pollAt time.Time) error
 		Fetch(ctx context.Context) ([]domain.Topic, error)
+		// FetchByPrefix returns topics which URL starts with prefix
+		// without their content.
+		FetchByPrefix(ctx context.Context, prefix *url.URL) ([]domain.Topic, error)
+		// FetchUpdated returns topics updated at or after since without
+		// their content.
+		FetchUpdated(ctx context.Context, since time.Time) ([]domain.Topic, error)
+		// FetchScheduled returns topics which polling is scheduled at or
+		// before ts without their content.
+		FetchScheduled(ctx context.Context, ts time.Time) ([]domain.Topic, error)
 		Get(ctx context.Context, u *url.URL) (*domain.Topic, error)
-		// TODO(toby3d): Delete(ctx context.Context, u *url.URL) (bool, error)
+		Delete(ctx context.Context, u *url.URL) (bool, error)
+		// Move re-keys topic stored by from to the to URL, replacing
+		// the existing one.
+		Move(ctx context.Context, from, to *url.URL) error
+	}
+
+	// VersionRepository keeps the last content versions of topics.
+	VersionRepository interface {
+		Create(ctx context.Context, version domain.TopicVersion) error
+		Get(ctx context.Context, u *url.URL, id uint64) (*domain.TopicVersion, error)
+		// Fetch returns kept versions of topic, the latest first.
+		Fetch(ctx context.Context, u *url.URL) ([]domain.TopicVersion, error)
+		// Prune deletes versions of topic except of the keep latest
+		// ones and returns their number.
+		Prune(ctx context.Context, u *url.URL, keep uint) (int, error)
+		// Delete deletes all versions of topic and returns their
+		// number.
+		Delete(ctx context.Context, u *url.URL) (int, error)
+		// Move re-keys versions of topic stored by from to the to URL,
+		// replacing the existing ones.
+		Move(ctx context.Context, from, to *url.URL) error
+	}
+
+	// Transactor runs fn in a single transaction which is shared by
+	// repositories through the ctx.
+	Transactor interface {
+		Transaction(ctx context.Context, fn func(ctx context.Context) error) error
 	}
 )
 
 var (
 	ErrExist    = errors.New("topic already exists")
 	ErrNotExist = errors.New("topic does not exist")
+	ErrGone     = errors.New("topic is gone")
+	ErrHub      = errors.New("topic does not advertise this hub")
+
+	ErrVersionNotExist = errors.New("topic version does not exist")
 )
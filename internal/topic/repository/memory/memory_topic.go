@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"net/url"
 	"sync"
+	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
 	"source.toby3d.me/toby3d/hub/internal/topic"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
 )
 
 type memoryTopicRepository struct {
@@ -23,21 +25,38 @@ func NewMemoryTopicRepository() topic.Repository {
 	}
 }
 
-func (repo *memoryTopicRepository) Update(ctx context.Context, u *url.URL, update topic.UpdateFunc) error {
-	tx, err := repo.Get(ctx, u)
+func (repo *memoryTopicRepository) Update(_ context.Context, u *url.URL, update topic.UpdateFunc) error {
+	// NOTE(toby3d): topic is read and written under the same lock, so
+	// concurrent updates never overwrite each other.
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	tx, ok := repo.topics[key(u)]
+	if !ok {
+		return fmt.Errorf("cannot find updating topic: %w", topic.ErrNotExist)
+	}
+
+	result, err := update(&tx)
 	if err != nil {
-		return fmt.Errorf("cannot find updating topic: %w", err)
+		return fmt.Errorf("cannot update topic: %w", err)
 	}
 
+	repo.topics[key(u)] = *result
+
+	return nil
+}
+
+func (repo *memoryTopicRepository) Reschedule(_ context.Context, u *url.URL, pollAt time.Time) error {
 	repo.mutex.Lock()
 	defer repo.mutex.Unlock()
 
-	result, err := update(tx)
-	if err != nil {
-		return fmt.Errorf("cannot update topic: %w", err)
+	t, ok := repo.topics[key(u)]
+	if !ok {
+		return fmt.Errorf("cannot find rescheduling topic: %w", topic.ErrNotExist)
 	}
 
-	repo.topics[u.String()] = *result
+	t.PollAt = pollAt
+	repo.topics[key(u)] = t
 
 	return nil
 }
@@ -55,7 +74,7 @@ func (repo *memoryTopicRepository) Create(ctx context.Context, u *url.URL, t dom
 	repo.mutex.Lock()
 	defer repo.mutex.Unlock()
 
-	repo.topics[u.String()] = t
+	repo.topics[key(u)] = t
 
 	return nil
 }
@@ -64,7 +83,7 @@ func (repo *memoryTopicRepository) Get(ctx context.Context, u *url.URL) (*domain
 	repo.mutex.RLock()
 	defer repo.mutex.RUnlock()
 
-	if out, ok := repo.topics[u.String()]; ok {
+	if out, ok := repo.topics[key(u)]; ok {
 		return &out, nil
 	}
 
@@ -83,3 +102,97 @@ func (repo *memoryTopicRepository) Fetch(_ context.Context) ([]domain.Topic, err
 
 	return out, nil
 }
+
+func (repo *memoryTopicRepository) FetchUpdated(_ context.Context, since time.Time) ([]domain.Topic, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Topic, 0)
+
+	for _, t := range repo.topics {
+		if t.UpdatedAt.Before(since) {
+			continue
+		}
+
+		t.Content = nil
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+func (repo *memoryTopicRepository) FetchScheduled(_ context.Context, ts time.Time) ([]domain.Topic, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Topic, 0)
+
+	for _, t := range repo.topics {
+		if t.PollAt.After(ts) {
+			continue
+		}
+
+		t.Content = nil
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+func (repo *memoryTopicRepository) FetchByPrefix(_ context.Context, prefix *url.URL) ([]domain.Topic, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.Topic, 0)
+
+	for _, t := range repo.topics {
+		if !urlutil.HasPrefix(t.Self, prefix) {
+			continue
+		}
+
+		t.Content = nil
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+func (repo *memoryTopicRepository) Delete(ctx context.Context, u *url.URL) (bool, error) {
+	if _, err := repo.Get(ctx, u); err != nil {
+		if !errors.Is(err, topic.ErrNotExist) {
+			return false, fmt.Errorf("cannot delete topic: %w", err)
+		}
+
+		return false, nil
+	}
+
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	delete(repo.topics, key(u))
+
+	return true, nil
+}
+
+func (repo *memoryTopicRepository) Move(_ context.Context, from, to *url.URL) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	t, ok := repo.topics[key(from)]
+	if !ok {
+		return nil
+	}
+
+	delete(repo.topics, key(from))
+
+	t.Self = to
+	repo.topics[key(to)] = t
+
+	return nil
+}
+
+// key returns the canonical form of topic URL, so equivalent URLs are stored
+// by the same key.
+func key(u *url.URL) string {
+	return urlutil.Canonical(u).String()
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/topic"
+)
+
+type memoryVersionRepository struct {
+	mutex    *sync.RWMutex
+	versions map[string][]domain.TopicVersion
+}
+
+func NewMemoryVersionRepository() topic.VersionRepository {
+	return &memoryVersionRepository{
+		mutex:    new(sync.RWMutex),
+		versions: make(map[string][]domain.TopicVersion),
+	}
+}
+
+func (repo *memoryVersionRepository) Create(_ context.Context, v domain.TopicVersion) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	versions := repo.versions[key(v.Topic)]
+
+	for i := range versions {
+		if versions[i].ID == v.ID {
+			versions[i] = v
+
+			return nil
+		}
+	}
+
+	versions = append(versions, v)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].ID > versions[j].ID
+	})
+
+	repo.versions[key(v.Topic)] = versions
+
+	return nil
+}
+
+func (repo *memoryVersionRepository) Get(_ context.Context, u *url.URL, id uint64) (*domain.TopicVersion, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	for _, v := range repo.versions[key(u)] {
+		if v.ID == id {
+			return &v, nil
+		}
+	}
+
+	return nil, topic.ErrVersionNotExist
+}
+
+func (repo *memoryVersionRepository) Fetch(_ context.Context, u *url.URL) ([]domain.TopicVersion, error) {
+	repo.mutex.RLock()
+	defer repo.mutex.RUnlock()
+
+	out := make([]domain.TopicVersion, len(repo.versions[key(u)]))
+	copy(out, repo.versions[key(u)])
+
+	return out, nil
+}
+
+func (repo *memoryVersionRepository) Prune(_ context.Context, u *url.URL, keep uint) (int, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	versions := repo.versions[key(u)]
+	if uint(len(versions)) <= keep {
+		return 0, nil
+	}
+
+	repo.versions[key(u)] = versions[:keep]
+
+	return len(versions) - int(keep), nil
+}
+
+func (repo *memoryVersionRepository) Delete(_ context.Context, u *url.URL) (int, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	count := len(repo.versions[key(u)])
+	delete(repo.versions, key(u))
+
+	return count, nil
+}
+
+func (repo *memoryVersionRepository) Move(_ context.Context, from, to *url.URL) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	versions, ok := repo.versions[key(from)]
+	delete(repo.versions, key(to))
+
+	if !ok {
+		return nil
+	}
+
+	delete(repo.versions, key(from))
+
+	for i := range versions {
+		versions[i].Topic = to
+	}
+
+	repo.versions[key(to)] = versions
+
+	return nil
+}
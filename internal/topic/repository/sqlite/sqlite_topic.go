@@ -4,24 +4,37 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
 	"source.toby3d.me/toby3d/hub/internal/topic"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
 )
 
 type (
 	Topic struct {
-		CreatedAt   DateTime `db:"created_at"`
-		UpdatedAt   DateTime `db:"updated_at"`
-		URL         URL      `db:"url"`
-		ContentType string   `db:"content_type"`
-		Content     []byte   `db:"content"`
+		CreatedAt    DateTime `db:"created_at"`
+		UpdatedAt    DateTime `db:"updated_at"`
+		URL          URL      `db:"url"`
+		ContentType  string   `db:"content_type"`
+		Content      []byte   `db:"content"`
+		Canonical    URL      `db:"canonical"`
+		ETag         string   `db:"etag"`
+		LastModified string   `db:"last_modified"`
+		Hash         string   `db:"hash"`
+		Entries      Entries  `db:"entries"`
+		Failures     uint     `db:"failures"`
+		PollAt       DateTime `db:"poll_at"`
+		PollInterval int64    `db:"poll_interval"`
+		VersionID    uint64   `db:"version_id"`
 	}
 
 	DateTime struct {
@@ -34,12 +47,23 @@ type (
 		Valid bool
 	}
 
+	// Entries is a JSON encoded records of topic feed entries.
+	Entries struct {
+		Entries []domain.Entry
+		Valid   bool
+	}
+
 	sqliteTopicRepository struct {
-		create *sqlx.NamedStmt
-		update *sqlx.NamedStmt
-		read   *sqlx.Stmt
-		fetch  *sqlx.Stmt
-		delete *sqlx.Stmt
+		create        *sqlx.NamedStmt
+		update        *sqlx.NamedStmt
+		reschedule    *sqlx.Stmt
+		read          *sqlx.Stmt
+		fetch         *sqlx.Stmt
+		fetchUpdated  *sqlx.Stmt
+		fetchByPrefix *sqlx.Stmt
+		fetchPoll     *sqlx.Stmt
+		delete        *sqlx.Stmt
+		move          *sqlx.Stmt
 	}
 )
 
@@ -50,19 +74,52 @@ const (
 		updated_at DATETIME,
 		url TEXT PRIMARY KEY,
 		content_type TEXT,
-		content BLOB
+		content BLOB,
+		canonical TEXT DEFAULT '',
+		etag TEXT DEFAULT '',
+		last_modified TEXT DEFAULT '',
+		hash TEXT DEFAULT '',
+		entries TEXT DEFAULT '',
+		failures INTEGER DEFAULT 0,
+		poll_at DATETIME DEFAULT 0,
+		poll_interval INTEGER DEFAULT 0,
+		version_id INTEGER DEFAULT 0
 	)`
-	queryIndex  string = `CREATE INDEX IF NOT EXISTS idx_topic ON ` + table + ` (url);`
-	queryCreate string = `INSERT INTO ` + table + ` (created_at, updated_at, url, content_type, content)
-		       		VALUES (:created_at, :updated_at, :url, :content_type, :content);`
-	queryFetch  string = `SELECT * FROM ` + table + `;`
-	queryRead   string = `SELECT * FROM ` + table + ` WHERE url = ?;`
-	queryUpdate string = `UPDATE ` + table + `
+	queryIndex string = `CREATE INDEX IF NOT EXISTS idx_topic ON ` + table + ` (url);
+		CREATE INDEX IF NOT EXISTS idx_topic_updated ON ` + table + ` (updated_at);
+		CREATE INDEX IF NOT EXISTS idx_topic_poll ON ` + table + ` (poll_at);`
+	queryCreate string = `INSERT INTO ` + table + ` (created_at, updated_at, url, content_type, content, canonical,
+					etag, last_modified, hash, entries, failures, poll_at, poll_interval, version_id)
+		       		VALUES (:created_at, :updated_at, :url, :content_type, :content, :canonical, :etag,
+					:last_modified, :hash, :entries, :failures, :poll_at, :poll_interval, :version_id);`
+	// NOTE(toby3d): every column except content, which is not needed for
+	// batch fetches.
+	columnsMeta string = `created_at, updated_at, url, content_type, canonical, etag, last_modified, hash,
+		entries, failures, poll_at, poll_interval, version_id`
+	queryFetch        string = `SELECT * FROM ` + table + `;`
+	queryFetchUpdated string = `SELECT ` + columnsMeta + ` FROM ` + table + ` WHERE updated_at >= ?;`
+	// NOTE(toby3d): range condition instead of LIKE keeps the url index
+	// in use and the comparison case-sensitive.
+	queryFetchByPrefix string = `SELECT ` + columnsMeta + ` FROM ` + table + ` WHERE url >= ? AND url < ?;`
+	queryFetchPoll     string = `SELECT ` + columnsMeta + ` FROM ` + table + ` WHERE poll_at <= ?;`
+	queryRead          string = `SELECT * FROM ` + table + ` WHERE url = ?;`
+	queryUpdate        string = `UPDATE ` + table + `
 				SET updated_at = :updated_at,
 					content_type = :content_type,
-					content = :content
+					content = :content,
+					canonical = :canonical,
+					etag = :etag,
+					last_modified = :last_modified,
+					hash = :hash,
+					entries = :entries,
+					failures = :failures,
+					poll_at = :poll_at,
+					poll_interval = :poll_interval,
+					version_id = :version_id
 				WHERE url = :url;`
-	queryDelete string = `DELETE FROM ` + table + ` WHERE url = ?;`
+	queryReschedule string = `UPDATE ` + table + ` SET poll_at = ? WHERE url = ?;`
+	queryDelete     string = `DELETE FROM ` + table + ` WHERE url = ?;`
+	queryMove       string = `UPDATE OR REPLACE ` + table + ` SET url = ? WHERE url = ?;`
 )
 
 func NewSQLiteTopicRepository(db *sqlx.DB) (topic.Repository, error) {
@@ -73,6 +130,28 @@ func NewSQLiteTopicRepository(db *sqlx.DB) (topic.Repository, error) {
 		return nil, fmt.Errorf("topic: sqlite: cannot prepare table: %w", err)
 	}
 
+	if err = sqlutil.AddColumns(db, table,
+		sqlutil.Column{Name: "canonical", Definition: "TEXT DEFAULT ''"},
+		sqlutil.Column{Name: "etag", Definition: "TEXT DEFAULT ''"},
+		sqlutil.Column{Name: "last_modified", Definition: "TEXT DEFAULT ''"},
+		sqlutil.Column{Name: "hash", Definition: "TEXT DEFAULT ''"},
+		sqlutil.Column{Name: "entries", Definition: "TEXT DEFAULT ''"},
+		sqlutil.Column{Name: "failures", Definition: "INTEGER DEFAULT 0"},
+		sqlutil.Column{Name: "poll_at", Definition: "DATETIME DEFAULT 0"},
+		sqlutil.Column{Name: "poll_interval", Definition: "INTEGER DEFAULT 0"},
+		sqlutil.Column{Name: "version_id", Definition: "INTEGER DEFAULT 0"},
+	); err != nil {
+		return nil, fmt.Errorf("topic: sqlite: cannot migrate table: %w", err)
+	}
+
+	// NOTE(toby3d): previous versions of the hub stored URLs as is, so
+	// equivalent URLs of the same resource may be stored in separate rows.
+	if err = sqlutil.Migrate(db, table+"_canonical_urls", func(tx *sqlx.Tx) error {
+		return sqlutil.MergeDuplicates(tx, table, "updated_at DESC", urlutil.CanonicalString, "url")
+	}); err != nil {
+		return nil, fmt.Errorf("topic: sqlite: cannot migrate table: %w", err)
+	}
+
 	for q, dst := range map[string]**sqlx.NamedStmt{
 		queryCreate: &out.create,
 		queryUpdate: &out.update,
@@ -83,9 +162,14 @@ func NewSQLiteTopicRepository(db *sqlx.DB) (topic.Repository, error) {
 	}
 
 	for q, dst := range map[string]**sqlx.Stmt{
-		queryDelete: &out.delete,
-		queryFetch:  &out.fetch,
-		queryRead:   &out.read,
+		queryDelete:        &out.delete,
+		queryReschedule:    &out.reschedule,
+		queryMove:          &out.move,
+		queryFetch:         &out.fetch,
+		queryFetchUpdated:  &out.fetchUpdated,
+		queryFetchByPrefix: &out.fetchByPrefix,
+		queryFetchPoll:     &out.fetchPoll,
+		queryRead:          &out.read,
 	} {
 		if *dst, err = db.Preparex(q); err != nil {
 			return nil, fmt.Errorf("topic: sqlite: cannot create prepared topic statement: %w", err)
@@ -100,10 +184,16 @@ func NewSQLiteTopicRepository(db *sqlx.DB) (topic.Repository, error) {
 }
 
 func (repo *sqliteTopicRepository) Create(ctx context.Context, u *url.URL, t domain.Topic) error {
+	if _, err := repo.Get(ctx, u); err == nil {
+		return fmt.Errorf("topic: sqlite: cannot create topic: %w", topic.ErrExist)
+	} else if !errors.Is(err, topic.ErrNotExist) {
+		return fmt.Errorf("topic: sqlite: cannot check topic: %w", err)
+	}
+
 	row := new(Topic)
 	row.bind(t)
 
-	if _, err := repo.create.ExecContext(ctx, row); err != nil {
+	if _, err := sqlutil.NamedStmt(ctx, repo.create).ExecContext(ctx, row); err != nil {
 		return fmt.Errorf("topic: sqlite: cannot create topic: %w", err)
 	}
 
@@ -111,17 +201,78 @@ func (repo *sqliteTopicRepository) Create(ctx context.Context, u *url.URL, t dom
 }
 
 func (repo *sqliteTopicRepository) Fetch(ctx context.Context) ([]domain.Topic, error) {
-	rows, err := repo.fetch.QueryxContext(ctx, nil)
+	rows, err := repo.fetch.QueryxContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("topic: sqlite: cannot fetch topics: %w", err)
 	}
+
+	return scan(rows)
+}
+
+func (repo *sqliteTopicRepository) FetchUpdated(ctx context.Context, since time.Time) ([]domain.Topic, error) {
+	rows, err := repo.fetchUpdated.QueryxContext(ctx, since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("topic: sqlite: cannot fetch updated topics: %w", err)
+	}
+
+	return scan(rows)
+}
+
+func (repo *sqliteTopicRepository) FetchByPrefix(ctx context.Context, prefix *url.URL) ([]domain.Topic, error) {
+	from := urlutil.Canonical(prefix).String()
+	if from == "" {
+		return repo.Fetch(ctx)
+	}
+
+	// NOTE(toby3d): prefix with trailing slash also contains URL without
+	// it, so range starts from the trimmed one.
+	from = strings.TrimSuffix(from, "/")
+
+	// NOTE(toby3d): the smallest string which is greater than every
+	// string with this prefix.
+	to := []byte(from)
+	to[len(to)-1]++
+
+	rows, err := repo.fetchByPrefix.QueryxContext(ctx, from, string(to))
+	if err != nil {
+		return nil, fmt.Errorf("topic: sqlite: cannot fetch topics by prefix: %w", err)
+	}
+
+	topics, err := scan(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE(toby3d): range condition matches string prefix only, so topics
+	// which path does not match prefix on segments boundary are skipped.
+	out := topics[:0]
+
+	for i := range topics {
+		if urlutil.HasPrefix(topics[i].Self, prefix) {
+			out = append(out, topics[i])
+		}
+	}
+
+	return out, nil
+}
+
+func (repo *sqliteTopicRepository) FetchScheduled(ctx context.Context, ts time.Time) ([]domain.Topic, error) {
+	rows, err := repo.fetchPoll.QueryxContext(ctx, ts.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("topic: sqlite: cannot fetch scheduled topics: %w", err)
+	}
+
+	return scan(rows)
+}
+
+func scan(rows *sqlx.Rows) ([]domain.Topic, error) {
 	defer rows.Close()
 
 	out := make([]domain.Topic, 0)
 
 	for rows.Next() {
 		row := new(Topic)
-		if err = rows.StructScan(row); err != nil {
+		if err := rows.StructScan(row); err != nil {
 			return nil, fmt.Errorf("topic: sqlite: cannot scan topics row: %w", err)
 		}
 
@@ -131,12 +282,20 @@ func (repo *sqliteTopicRepository) Fetch(ctx context.Context) ([]domain.Topic, e
 		out = append(out, t)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("topic: sqlite: cannot read topics rows: %w", err)
+	}
+
 	return out, nil
 }
 
 func (repo *sqliteTopicRepository) Get(ctx context.Context, u *url.URL) (*domain.Topic, error) {
 	row := new(Topic)
-	if err := repo.read.GetContext(ctx, row, u.String()); err != nil {
+	if err := sqlutil.Stmt(ctx, repo.read).GetContext(ctx, row, urlutil.Canonical(u).String()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, topic.ErrNotExist
+		}
+
 		return nil, fmt.Errorf("topic: sqlite: cannot get topic row: %w", err)
 	}
 
@@ -160,15 +319,43 @@ func (repo *sqliteTopicRepository) Update(ctx context.Context, u *url.URL, updat
 	row := new(Topic)
 	row.bind(*out)
 
-	if _, err = repo.update.ExecContext(ctx, row); err != nil {
+	if _, err = sqlutil.NamedStmt(ctx, repo.update).ExecContext(ctx, row); err != nil {
 		return fmt.Errorf("topic: sqlite: cannot update topic row: %w", err)
 	}
 
 	return nil
 }
 
+func (repo *sqliteTopicRepository) Reschedule(ctx context.Context, u *url.URL, pollAt time.Time) error {
+	result, err := sqlutil.Stmt(ctx, repo.reschedule).ExecContext(ctx, NewDateTime(pollAt),
+		urlutil.Canonical(u).String())
+	if err != nil {
+		return fmt.Errorf("topic: sqlite: cannot reschedule topic: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("topic: sqlite: cannot read affected rescheduled rows result: %w", err)
+	}
+
+	if count == 0 {
+		return fmt.Errorf("topic: sqlite: cannot find rescheduling topic: %w", topic.ErrNotExist)
+	}
+
+	return nil
+}
+
+func (repo *sqliteTopicRepository) Move(ctx context.Context, from, to *url.URL) error {
+	if _, err := sqlutil.Stmt(ctx, repo.move).ExecContext(ctx, urlutil.Canonical(to).String(),
+		urlutil.Canonical(from).String()); err != nil {
+		return fmt.Errorf("topic: sqlite: cannot move topic: %w", err)
+	}
+
+	return nil
+}
+
 func (repo *sqliteTopicRepository) Delete(ctx context.Context, u *url.URL) (bool, error) {
-	result, err := repo.delete.ExecContext(ctx, u.String())
+	result, err := repo.delete.ExecContext(ctx, urlutil.Canonical(u).String())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
@@ -188,6 +375,15 @@ func (repo *sqliteTopicRepository) Delete(ctx context.Context, u *url.URL) (bool
 func (t *Topic) bind(src domain.Topic) {
 	t.Content = src.Content
 	t.ContentType = src.ContentType
+	t.Canonical = NewURL(src.Canonical)
+	t.ETag = src.ETag
+	t.LastModified = src.LastModified
+	t.Hash = src.Hash
+	t.Entries = NewEntries(src.Entries)
+	t.Failures = src.Failures
+	t.PollAt = NewDateTime(src.PollAt)
+	t.PollInterval = int64(src.PollInterval.Seconds())
+	t.VersionID = src.VersionID
 	t.CreatedAt = NewDateTime(src.CreatedAt)
 	t.UpdatedAt = NewDateTime(src.UpdatedAt)
 	t.URL = NewURL(src.Self)
@@ -196,6 +392,15 @@ func (t *Topic) bind(src domain.Topic) {
 func (t Topic) populate(dst *domain.Topic) {
 	dst.Content = t.Content
 	dst.ContentType = t.ContentType
+	dst.Canonical = t.Canonical.URL
+	dst.ETag = t.ETag
+	dst.LastModified = t.LastModified
+	dst.Hash = t.Hash
+	dst.Entries = t.Entries.Entries
+	dst.Failures = t.Failures
+	dst.PollAt = t.PollAt.DateTime
+	dst.PollInterval = time.Duration(t.PollInterval) * time.Second
+	dst.VersionID = t.VersionID
 	dst.CreatedAt = t.CreatedAt.DateTime
 	dst.Self = t.URL.URL
 	dst.UpdatedAt = t.UpdatedAt.DateTime
@@ -213,12 +418,20 @@ func (u *URL) Scan(src any) error {
 
 	switch s := src.(type) {
 	case []byte:
+		if len(s) == 0 {
+			return nil
+		}
+
 		if u.URL, err = url.Parse(string(s)); err != nil {
 			return fmt.Errorf("URL: cannot scan BLOB value as URL: %w", err)
 		}
 
 		u.Valid = true
 	case string:
+		if s == "" {
+			return nil
+		}
+
 		if u.URL, err = url.Parse(s); err != nil {
 			return fmt.Errorf("URL: cannot scan TEXT value as URL: %w", err)
 		}
@@ -234,7 +447,50 @@ func (u URL) Value() (driver.Value, error) {
 		return "", nil
 	}
 
-	return u.URL.String(), nil
+	return urlutil.Canonical(u.URL).String(), nil
+}
+
+func NewEntries(entries []domain.Entry) Entries {
+	return Entries{
+		Entries: entries,
+		Valid:   len(entries) > 0,
+	}
+}
+
+func (e *Entries) Scan(src any) error {
+	var raw []byte
+
+	switch s := src.(type) {
+	case []byte:
+		raw = s
+	case string:
+		raw = []byte(s)
+	}
+
+	if len(raw) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(raw, &e.Entries); err != nil {
+		return fmt.Errorf("Entries: cannot scan TEXT value as entries: %w", err)
+	}
+
+	e.Valid = true
+
+	return nil
+}
+
+func (e Entries) Value() (driver.Value, error) {
+	if !e.Valid {
+		return "", nil
+	}
+
+	raw, err := json.Marshal(e.Entries)
+	if err != nil {
+		return nil, fmt.Errorf("Entries: cannot encode entries: %w", err)
+	}
+
+	return string(raw), nil
 }
 
 func NewDateTime(t time.Time) DateTime {
@@ -256,7 +512,7 @@ func (dt *DateTime) Scan(src any) error {
 
 func (dt DateTime) Value() (driver.Value, error) {
 	if !dt.Valid {
-		return 0, nil
+		return int64(0), nil
 	}
 
 	return dt.DateTime.Unix(), nil
@@ -3,6 +3,8 @@ package sqlite_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"net/url"
 	"path/filepath"
 	"testing"
 	"time"
@@ -12,6 +14,7 @@ import (
 	_ "modernc.org/sqlite"
 
 	"source.toby3d.me/toby3d/hub/internal/domain"
+	topicpkg "source.toby3d.me/toby3d/hub/internal/topic"
 	repository "source.toby3d.me/toby3d/hub/internal/topic/repository/sqlite"
 )
 
@@ -28,6 +31,7 @@ func Test(t *testing.T) {
 	}
 
 	topic := domain.TestTopic(t)
+	topic.Entries = []domain.Entry{{UpdatedAt: topic.UpdatedAt, ID: "urn:lipsum", Hash: topic.Hash}}
 
 	// NOTE(toby3d): Create test.
 	if err = repo.Create(context.Background(), topic.Self, *topic); err != nil {
@@ -44,6 +48,25 @@ func Test(t *testing.T) {
 		t.Error(diff)
 	}
 
+	// NOTE(toby3d): FetchByPrefix test depends from Create.
+	for prefix, expect := range map[string]int{
+		topic.Self.String():                                     1,
+		topic.Self.Scheme + "://" + topic.Self.Host:             1,
+		topic.Self.Scheme + "://" + topic.Self.Host + "/lipsum": 0,
+		"https://example.org/":                                  0,
+	} {
+		u, _ := url.Parse(prefix)
+
+		topics, err := repo.FetchByPrefix(context.Background(), u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(topics) != expect {
+			t.Errorf("FetchByPrefix(%s) = %d topics, want %d", prefix, len(topics), expect)
+		}
+	}
+
 	// NOTE(toby3d): Update test depend from Create.
 	now := time.Now().UTC().Round(time.Second)
 	content := []byte("lorem ipsum")
@@ -69,7 +92,51 @@ func Test(t *testing.T) {
 		t.Errorf("want '%s', got '%s'", string(content), string(actual.Content))
 	}
 
-	/* NOTE(toby3d): Delete test depend from Create.
+	// NOTE(toby3d): Reschedule test depends from Create.
+	pollAt := now.Add(time.Hour)
+
+	if err = repo.Reschedule(context.Background(), topic.Self, pollAt); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, err = repo.Get(context.Background(), topic.Self); err != nil {
+		t.Fatal(err)
+	}
+
+	if !actual.PollAt.Equal(pollAt) || !bytes.Equal(actual.Content, content) {
+		t.Errorf("want only poll at '%s', got '%s'", pollAt.Format(time.RFC3339),
+			actual.PollAt.Format(time.RFC3339))
+	}
+
+	if err = repo.Reschedule(context.Background(), topic.Self.JoinPath("missing"),
+		pollAt); !errors.Is(err, topicpkg.ErrNotExist) {
+		t.Errorf("want %v error, got %v", topicpkg.ErrNotExist, err)
+	}
+
+	// NOTE(toby3d): Move test depends from Create.
+	moved := topic.Self.JoinPath("moved")
+
+	if err = repo.Move(context.Background(), topic.Self, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = repo.Get(context.Background(), topic.Self); !errors.Is(err, topicpkg.ErrNotExist) {
+		t.Errorf("want %v error, got %v", topicpkg.ErrNotExist, err)
+	}
+
+	if actual, err = repo.Get(context.Background(), moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.Self.String() != moved.String() {
+		t.Errorf("want self %s, got %s", moved, actual.Self)
+	}
+
+	if err = repo.Move(context.Background(), moved, topic.Self); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE(toby3d): Delete test depend from Create.
 	ok, err := repo.Delete(context.Background(), topic.Self)
 	if err != nil {
 		t.Fatal(err)
@@ -79,8 +146,45 @@ func Test(t *testing.T) {
 		t.Errorf("want %t, got %t", true, ok)
 	}
 
-	if _, err = repo.Get(context.Background(), topic.Self); !errors.Is(err, sql.ErrNoRows) {
-		t.Errorf("want %v error, got %v", sql.ErrNoRows, err)
+	if _, err = repo.Get(context.Background(), topic.Self); !errors.Is(err, topicpkg.ErrNotExist) {
+		t.Errorf("want %v error, got %v", topicpkg.ErrNotExist, err)
+	}
+}
+
+func TestFetchByPrefix(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLiteTopicRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/blog", "/blog/post", "/blog-evil", "/blogs/post"} {
+		topic := domain.TestTopic(t)
+		topic.Self = topic.Self.JoinPath(path)
+
+		if err = repo.Create(context.Background(), topic.Self, *topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for prefix, expect := range map[string]int{
+		"https://example.com/blog":  2,
+		"https://example.com/blog/": 2,
+		"https://example.com/":      4,
+	} {
+		u, _ := url.Parse(prefix)
+
+		topics, err := repo.FetchByPrefix(context.Background(), u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(topics) != expect {
+			t.Errorf("FetchByPrefix(%s) = %d topics, want %d", prefix, len(topics), expect)
+		}
 	}
-	*/
 }
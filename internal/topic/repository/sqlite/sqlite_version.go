@@ -0,0 +1,205 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/jmoiron/sqlx"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+	"source.toby3d.me/toby3d/hub/internal/topic"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+type (
+	Version struct {
+		UpdatedAt   DateTime `db:"updated_at"`
+		Topic       URL      `db:"topic"`
+		ContentType string   `db:"content_type"`
+		Content     []byte   `db:"content"`
+		Hash        string   `db:"hash"`
+		ID          uint64   `db:"id"`
+	}
+
+	sqliteVersionRepository struct {
+		create *sqlx.NamedStmt
+		read   *sqlx.Stmt
+		fetch  *sqlx.Stmt
+		prune  *sqlx.Stmt
+		delete *sqlx.Stmt
+		move   *sqlx.Stmt
+	}
+)
+
+const (
+	tableVersions      string = "topic_versions"
+	queryVersionsTable string = `CREATE TABLE IF NOT EXISTS ` + tableVersions + ` (
+		updated_at DATETIME,
+		topic TEXT,
+		content_type TEXT DEFAULT '',
+		content BLOB,
+		hash TEXT DEFAULT '',
+		id INTEGER,
+		PRIMARY KEY (topic, id)
+	)`
+	queryVersionsCreate string = `INSERT OR REPLACE INTO ` + tableVersions + ` (updated_at, topic, content_type, ` +
+		`content, hash, id)
+		VALUES (:updated_at, :topic, :content_type, :content, :hash, :id);`
+	queryVersionsRead  string = `SELECT * FROM ` + tableVersions + ` WHERE topic = ? AND id = ?;`
+	queryVersionsFetch string = `SELECT * FROM ` + tableVersions + ` WHERE topic = ? ORDER BY id DESC;`
+	// NOTE(toby3d): LIMIT -1 is an unlimited rows count in SQLite, which
+	// is required by OFFSET.
+	queryVersionsPrune string = `DELETE FROM ` + tableVersions + ` WHERE topic = ? AND id IN (
+		SELECT id FROM ` + tableVersions + ` WHERE topic = ? ORDER BY id DESC LIMIT -1 OFFSET ?);`
+	queryVersionsDelete string = `DELETE FROM ` + tableVersions + ` WHERE topic = ?;`
+	queryVersionsMove   string = `UPDATE OR REPLACE ` + tableVersions + ` SET topic = ? WHERE topic = ?;`
+)
+
+func NewSQLiteVersionRepository(db *sqlx.DB) (topic.VersionRepository, error) {
+	out := new(sqliteVersionRepository)
+
+	var err error
+	if _, err = db.Exec(queryVersionsTable); err != nil {
+		return nil, fmt.Errorf("version: sqlite: cannot prepare table: %w", err)
+	}
+
+	if out.create, err = db.PrepareNamed(queryVersionsCreate); err != nil {
+		return nil, fmt.Errorf("version: sqlite: cannot create prepared named version statement: %w", err)
+	}
+
+	for q, dst := range map[string]**sqlx.Stmt{
+		queryVersionsRead:   &out.read,
+		queryVersionsFetch:  &out.fetch,
+		queryVersionsPrune:  &out.prune,
+		queryVersionsDelete: &out.delete,
+		queryVersionsMove:   &out.move,
+	} {
+		if *dst, err = db.Preparex(q); err != nil {
+			return nil, fmt.Errorf("version: sqlite: cannot create prepared version statement: %w", err)
+		}
+	}
+
+	return out, nil
+}
+
+func (repo *sqliteVersionRepository) Create(ctx context.Context, v domain.TopicVersion) error {
+	row := new(Version)
+	row.bind(v)
+
+	if _, err := sqlutil.NamedStmt(ctx, repo.create).ExecContext(ctx, row); err != nil {
+		return fmt.Errorf("version: sqlite: cannot create version: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *sqliteVersionRepository) Get(ctx context.Context, u *url.URL, id uint64) (*domain.TopicVersion, error) {
+	row := new(Version)
+	if err := repo.read.GetContext(ctx, row, urlutil.Canonical(u).String(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, topic.ErrVersionNotExist
+		}
+
+		return nil, fmt.Errorf("version: sqlite: cannot get version row: %w", err)
+	}
+
+	out := new(domain.TopicVersion)
+	row.populate(out)
+
+	return out, nil
+}
+
+func (repo *sqliteVersionRepository) Fetch(ctx context.Context, u *url.URL) ([]domain.TopicVersion, error) {
+	rows, err := repo.fetch.QueryxContext(ctx, urlutil.Canonical(u).String())
+	if err != nil {
+		return nil, fmt.Errorf("version: sqlite: cannot fetch versions: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]domain.TopicVersion, 0)
+
+	for rows.Next() {
+		row := new(Version)
+		if err = rows.StructScan(row); err != nil {
+			return nil, fmt.Errorf("version: sqlite: cannot scan versions row: %w", err)
+		}
+
+		var v domain.TopicVersion
+		row.populate(&v)
+
+		out = append(out, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("version: sqlite: cannot read versions rows: %w", err)
+	}
+
+	return out, nil
+}
+
+func (repo *sqliteVersionRepository) Prune(ctx context.Context, u *url.URL, keep uint) (int, error) {
+	key := urlutil.Canonical(u).String()
+
+	result, err := sqlutil.Stmt(ctx, repo.prune).ExecContext(ctx, key, key, keep)
+	if err != nil {
+		return 0, fmt.Errorf("version: sqlite: cannot prune versions: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("version: sqlite: cannot read affected pruned rows result: %w", err)
+	}
+
+	return int(count), nil
+}
+
+func (repo *sqliteVersionRepository) Delete(ctx context.Context, u *url.URL) (int, error) {
+	result, err := sqlutil.Stmt(ctx, repo.delete).ExecContext(ctx, urlutil.Canonical(u).String())
+	if err != nil {
+		return 0, fmt.Errorf("version: sqlite: cannot delete versions: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("version: sqlite: cannot read affected deleted rows result: %w", err)
+	}
+
+	return int(count), nil
+}
+
+func (repo *sqliteVersionRepository) Move(ctx context.Context, from, to *url.URL) error {
+	// NOTE(toby3d): versions of the replaced topic does not belong to the
+	// moved one, even if their identifiers are not conflict.
+	if _, err := repo.Delete(ctx, to); err != nil {
+		return fmt.Errorf("version: sqlite: cannot move versions: %w", err)
+	}
+
+	if _, err := sqlutil.Stmt(ctx, repo.move).ExecContext(ctx, urlutil.Canonical(to).String(),
+		urlutil.Canonical(from).String()); err != nil {
+		return fmt.Errorf("version: sqlite: cannot move versions: %w", err)
+	}
+
+	return nil
+}
+
+func (v *Version) bind(src domain.TopicVersion) {
+	v.UpdatedAt = NewDateTime(src.UpdatedAt)
+	v.Topic = NewURL(src.Topic)
+	v.ContentType = src.ContentType
+	v.Content = src.Content
+	v.Hash = src.Hash
+	v.ID = src.ID
+}
+
+func (v Version) populate(dst *domain.TopicVersion) {
+	dst.UpdatedAt = v.UpdatedAt.DateTime
+	dst.Topic = v.Topic.URL
+	dst.ContentType = v.ContentType
+	dst.Content = v.Content
+	dst.Hash = v.Hash
+	dst.ID = v.ID
+}
@@ -0,0 +1,105 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+	topicpkg "source.toby3d.me/toby3d/hub/internal/topic"
+	repository "source.toby3d.me/toby3d/hub/internal/topic/repository/sqlite"
+)
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	tdb := sqlx.MustOpen("sqlite", filepath.Join(t.TempDir(), "testing.db"))
+	t.Cleanup(func() { _ = tdb.Close() })
+
+	repo, err := repository.NewSQLiteVersionRepository(tdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic := domain.TestTopic(t)
+	in := make([]domain.TopicVersion, 0, 3)
+
+	// NOTE(toby3d): Create test.
+	for i, content := range []string{"one", "two", "three"} {
+		topic.Content = []byte(content)
+		topic.Hash = domain.NewContentHash(topic.Content)
+		topic.VersionID = uint64(i + 1)
+
+		if err = repo.Create(context.Background(), topic.Version()); err != nil {
+			t.Fatal(err)
+		}
+
+		in = append(in, topic.Version())
+	}
+
+	// NOTE(toby3d): Get test depends from Create.
+	actual, err := repo.Get(context.Background(), topic.Self, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, &in[1]); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err = repo.Get(context.Background(), topic.Self, 4); !errors.Is(err, topicpkg.ErrVersionNotExist) {
+		t.Errorf("want %v error, got %v", topicpkg.ErrVersionNotExist, err)
+	}
+
+	// NOTE(toby3d): Prune test depends from Create.
+	count, err := repo.Prune(context.Background(), topic.Self, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Errorf("want %d pruned versions, got %d", 1, count)
+	}
+
+	// NOTE(toby3d): Fetch test depends from Prune.
+	versions, err := repo.Fetch(context.Background(), topic.Self)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(versions, []domain.TopicVersion{in[2], in[1]}); diff != "" {
+		t.Error(diff)
+	}
+
+	// NOTE(toby3d): Move test depends from Create.
+	moved := topic.Self.JoinPath("moved")
+
+	if err = sqlutil.NewTransactor(tdb).Transaction(context.Background(), func(ctx context.Context) error {
+		return repo.Move(ctx, topic.Self, moved)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if versions, err = repo.Fetch(context.Background(), moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(versions) != 2 {
+		t.Errorf("want %d moved versions, got %d", 2, len(versions))
+	}
+
+	// NOTE(toby3d): Delete test depends from Move.
+	if count, err = repo.Delete(context.Background(), moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 2 {
+		t.Errorf("want %d deleted versions, got %d", 2, count)
+	}
+}
@@ -2,9 +2,26 @@ package topic
 
 import (
 	"context"
+	"errors"
 	"net/url"
+	"time"
+
+	"source.toby3d.me/toby3d/hub/internal/domain"
 )
 
 type UseCase interface {
+	// Publish fetches and stores a new content of topic. It returns false
+	// if content is not modified since the last fetch, or ErrGone if topic
+	// is repeatedly reported as removed and must be retired.
 	Publish(ctx context.Context, u *url.URL) (bool, error)
+	// PublishContent stores a new content of topic provided by publisher
+	// without fetching it. It returns false if content is not modified.
+	PublishContent(ctx context.Context, t domain.Topic) (bool, error)
+	// Schedule overrides the adaptive polling interval of topic, zero
+	// interval restores it.
+	Schedule(ctx context.Context, u *url.URL, interval time.Duration) (bool, error)
+	// Search returns known topics which URL starts with prefix.
+	Search(ctx context.Context, prefix *url.URL) ([]domain.Topic, error)
 }
+
+var ErrStatus = errors.New("topic replied with a non 2xx status")
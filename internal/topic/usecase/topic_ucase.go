@@ -10,57 +10,457 @@ import (
 	"time"
 
 	"source.toby3d.me/toby3d/hub/internal/common"
+	"source.toby3d.me/toby3d/hub/internal/discovery"
 	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/feed"
+	"source.toby3d.me/toby3d/hub/internal/httputil"
+	"source.toby3d.me/toby3d/hub/internal/queue"
+	"source.toby3d.me/toby3d/hub/internal/subscription"
 	"source.toby3d.me/toby3d/hub/internal/topic"
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
 )
 
-type topicUseCase struct {
-	client *http.Client
-	topics topic.Repository
-}
+type (
+	NewTopicUseCaseParams struct {
+		Topics topic.Repository
+		Client *http.Client
+		// Updates receives published topics, if not nil.
+		Updates chan<- domain.Topic
+		// RetireAfter is a number of consecutive 404/410 fetches after
+		// which topic is gone. Zero disables it.
+		RetireAfter uint
+		// Minimum and maximum intervals of adaptive topics polling.
+		PollInterval    time.Duration
+		PollIntervalMax time.Duration
+		// Hub is the URL of this hub which fetched and directly
+		// published topics must advertise, if not nil. Content of
+		// direct publishing has no response headers, so it must
+		// advertise the hub by itself.
+		Hub *url.URL
+		// Subscriptions and Queue are moved with topic which was
+		// permanently redirected.
+		Subscriptions subscription.Repository
+		Queue         queue.Repository
+		// Transactor runs topic moving and content storing with it's
+		// version in a single transaction, if not nil.
+		Transactor topic.Transactor
+		// Versions keeps the KeepVersions last content versions of
+		// each topic, if not nil. Zero KeepVersions disables it.
+		Versions     topic.VersionRepository
+		KeepVersions uint
+	}
+
+	topicUseCase struct {
+		client          *http.Client
+		topics          topic.Repository
+		updates         chan<- domain.Topic
+		retireAfter     uint
+		pollInterval    time.Duration
+		pollIntervalMax time.Duration
+		hub             *url.URL
+		subscriptions   subscription.Repository
+		queue           queue.Repository
+		transactor      topic.Transactor
+		versions        topic.VersionRepository
+		keepVersions    uint
+	}
+)
 
-func NewTopicUseCase(topics topic.Repository, client *http.Client) topic.UseCase {
+func NewTopicUseCase(params NewTopicUseCaseParams) topic.UseCase {
 	return &topicUseCase{
-		topics: topics,
-		client: client,
+		client:          params.Client,
+		topics:          params.Topics,
+		updates:         params.Updates,
+		retireAfter:     params.RetireAfter,
+		pollInterval:    params.PollInterval,
+		pollIntervalMax: params.PollIntervalMax,
+		hub:             params.Hub,
+		subscriptions:   params.Subscriptions,
+		queue:           params.Queue,
+		transactor:      params.Transactor,
+		versions:        params.Versions,
+		keepVersions:    params.KeepVersions,
 	}
 }
 
+// pollBackoff is a ratio of the time since the last topic content change to
+// the next adaptive polling interval.
+const pollBackoff time.Duration = 4
+
+// maxRedirects is the same limit of redirects as in the default http.Client.
+const maxRedirects int = 10
+
 func (ucase *topicUseCase) Publish(ctx context.Context, u *url.URL) (bool, error) {
 	now := time.Now().UTC().Round(time.Second)
 
-	resp, err := ucase.client.Get(u.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, fmt.Errorf("cannot build publishing url request: %w", err)
+	}
+
+	// NOTE(toby3d): ask publisher for content only if it was changed since
+	// the last fetch.
+	known, err := ucase.topics.Get(ctx, u)
+	if err != nil && !errors.Is(err, topic.ErrNotExist) {
+		return false, fmt.Errorf("cannot check publishing topic: %w", err)
+	}
+
+	if known != nil {
+		if known.ETag != "" {
+			req.Header.Set(common.HeaderIfNoneMatch, known.ETag)
+		}
+
+		if known.LastModified != "" {
+			req.Header.Set(common.HeaderIfModifiedSince, known.LastModified)
+		}
+	}
+
+	// NOTE(toby3d): remember the target of permanent redirects chain from
+	// the topic URL. Temporary redirect after them does not move topic.
+	var moved *url.URL
+
+	client, permanent := *ucase.client, true
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			if permanent {
+				moved = urlutil.Canonical(req.URL)
+			}
+		default:
+			permanent = false
+		}
+
+		if ucase.client.CheckRedirect != nil {
+			return ucase.client.CheckRedirect(req, via)
+		}
+
+		return nil
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return false, fmt.Errorf("cannot fetch publishing url: %w", err)
 	}
+	defer resp.Body.Close()
+
+	if moved != nil && moved.String() != u.String() && resp.StatusCode < http.StatusBadRequest {
+		if err = ucase.move(ctx, u, moved); err != nil {
+			return false, err
+		}
+
+		u = moved
+	}
+
+	changedAt := now
+	if known != nil {
+		changedAt = known.UpdatedAt
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && known != nil:
+		if etag := resp.Header.Get(common.HeaderETag); etag != "" {
+			known.ETag = etag
+		}
+
+		known.PollAt = ucase.schedule(known, resp.Header, changedAt, now)
+
+		return ucase.store(ctx, u, *known)
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return false, ucase.fail(ctx, u, resp.StatusCode, ucase.schedule(known, resp.Header, changedAt, now))
+	case resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices:
+		if err = ucase.reschedule(ctx, u, ucase.schedule(known, resp.Header, changedAt, now)); err != nil {
+			return false, err
+		}
+
+		return false, fmt.Errorf("%w: %d", topic.ErrStatus, resp.StatusCode)
+	}
 
 	content, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return false, fmt.Errorf("cannot read topic response body: %w", err)
 	}
 
+	links := discovery.Discover(resp.Request.URL, resp.Header, content)
+	if ucase.hub != nil && !links.Advertises(ucase.hub) {
+		return false, fmt.Errorf("%w: %s", topic.ErrHub, u)
+	}
+
+	hash := domain.NewContentHash(content)
+	if known == nil || known.Hash != hash {
+		changedAt = now
+	}
+
+	return ucase.store(ctx, u, domain.Topic{
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Self:         u,
+		ContentType:  resp.Header.Get(common.HeaderContentType),
+		Content:      content,
+		Canonical:    links.Self,
+		ETag:         resp.Header.Get(common.HeaderETag),
+		LastModified: resp.Header.Get(common.HeaderLastModified),
+		Hash:         hash,
+		PollAt:       ucase.schedule(known, resp.Header, changedAt, now),
+	})
+}
+
+func (ucase *topicUseCase) PublishContent(ctx context.Context, t domain.Topic) (bool, error) {
+	now := time.Now().UTC().Round(time.Second)
+
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	t.Hash = domain.NewContentHash(t.Content)
+	links := discovery.Discover(t.Self, http.Header{common.HeaderContentType: {t.ContentType}}, t.Content)
+	if ucase.hub != nil && !links.Advertises(ucase.hub) {
+		return false, fmt.Errorf("%w: %s", topic.ErrHub, t.Self)
+	}
+
+	t.Canonical = links.Self
+	// NOTE(toby3d): publisher sends updates by itself, so there is no
+	// reason to poll topic soon.
+	t.PollAt = now.Add(ucase.pollIntervalMax)
+
+	return ucase.store(ctx, t.Self, t)
+}
+
+func (ucase *topicUseCase) Schedule(ctx context.Context, u *url.URL, interval time.Duration) (bool, error) {
+	now := time.Now().UTC().Round(time.Second)
+
 	if err := ucase.topics.Update(ctx, u, func(tx *domain.Topic) (*domain.Topic, error) {
-		tx.Self = resp.Request.URL
-		tx.UpdatedAt = now
-		tx.Content = content
-		tx.ContentType = resp.Header.Get(common.HeaderContentType)
+		tx.PollInterval = interval
+
+		if pollAt := now.Add(interval); interval > 0 && pollAt.Before(tx.PollAt) {
+			tx.PollAt = pollAt
+		}
+
+		return tx, nil
+	}); err != nil {
+		if errors.Is(err, topic.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot schedule topic polling: %w", err)
+	}
+
+	return true, nil
+}
+
+func (ucase *topicUseCase) Search(ctx context.Context, prefix *url.URL) ([]domain.Topic, error) {
+	topics, err := ucase.topics.FetchByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("cannot search topics by prefix: %w", err)
+	}
+
+	return topics, nil
+}
+
+// fail counts a fetch of known topic which reports that it was removed and
+// returns topic.ErrGone if there are too many of them in a row.
+func (ucase *topicUseCase) fail(ctx context.Context, u *url.URL, status int, pollAt time.Time) error {
+	var failures uint
+
+	if err := ucase.topics.Update(ctx, u, func(tx *domain.Topic) (*domain.Topic, error) {
+		tx.Failures++
+		tx.PollAt = pollAt
+		failures = tx.Failures
+
+		return tx, nil
+	}); err != nil && !errors.Is(err, topic.ErrNotExist) {
+		return fmt.Errorf("cannot count topic fetch failure: %w", err)
+	}
+
+	if ucase.retireAfter > 0 && failures >= ucase.retireAfter {
+		return fmt.Errorf("%w: %d", topic.ErrGone, status)
+	}
+
+	return fmt.Errorf("%w: %d", topic.ErrStatus, status)
+}
+
+// move re-keys topic with all it's subscriptions, queued deliveries and
+// versions from the old URL to the new one in a single transaction, if any.
+func (ucase *topicUseCase) move(ctx context.Context, from, to *url.URL) error {
+	fn := func(ctx context.Context) error {
+		if err := ucase.topics.Move(ctx, from, to); err != nil {
+			return fmt.Errorf("cannot move topic: %w", err)
+		}
+
+		if ucase.subscriptions != nil {
+			if err := ucase.subscriptions.Move(ctx, from, to); err != nil {
+				return fmt.Errorf("cannot move topic subscriptions: %w", err)
+			}
+		}
+
+		if ucase.queue != nil {
+			if err := ucase.queue.Move(ctx, from, to); err != nil {
+				return fmt.Errorf("cannot move topic deliveries: %w", err)
+			}
+		}
+
+		if ucase.versions != nil {
+			if err := ucase.versions.Move(ctx, from, to); err != nil {
+				return fmt.Errorf("cannot move topic versions: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	return ucase.transaction(ctx, fn)
+}
+
+// transaction calls fn in a single transaction, if any.
+func (ucase *topicUseCase) transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ucase.transactor == nil {
+		return fn(ctx)
+	}
+
+	return ucase.transactor.Transaction(ctx, fn)
+}
+
+// reschedule sets the next polling time of known topic.
+func (ucase *topicUseCase) reschedule(ctx context.Context, u *url.URL, pollAt time.Time) error {
+	if err := ucase.topics.Reschedule(ctx, u, pollAt); err != nil && !errors.Is(err, topic.ErrNotExist) {
+		return fmt.Errorf("cannot reschedule topic polling: %w", err)
+	}
+
+	return nil
+}
+
+// schedule returns the next polling time of topic fetched at ts which content
+// was changed at changedAt. Rarely changing topics are polled rarely, but never
+// earlier than caching and Retry-After headers of publisher response allow.
+func (ucase *topicUseCase) schedule(t *domain.Topic, header http.Header, changedAt, ts time.Time) time.Time {
+	interval := ts.Sub(changedAt) / pollBackoff
+	if interval < ucase.pollInterval {
+		interval = ucase.pollInterval
+	}
+
+	if interval > ucase.pollIntervalMax {
+		interval = ucase.pollIntervalMax
+	}
+
+	if t != nil && t.PollInterval > 0 {
+		interval = t.PollInterval
+	}
+
+	if freshness, ok := httputil.Freshness(header, ts); ok {
+		if freshness > ucase.pollIntervalMax {
+			freshness = ucase.pollIntervalMax
+		}
+
+		if freshness > interval {
+			interval = freshness
+		}
+	}
+
+	if delay, ok := httputil.RetryAfter(header.Get(common.HeaderRetryAfter), ts); ok && delay > interval {
+		interval = delay
+	}
+
+	return ts.Add(interval)
+}
+
+// store updates the content of topic stored by u or creates a new one, and
+// notifies about it. Content with the same hash as already stored one is not
+// an update, so it's only validators are refreshed and false is returned.
+func (ucase *topicUseCase) store(ctx context.Context, u *url.URL, in domain.Topic) (bool, error) {
+	var (
+		out     domain.Topic
+		changed bool
+	)
+
+	// NOTE(toby3d): concurrent publishes of the same topic are serialized
+	// by transaction, so each content change gets it's own version, which
+	// is kept together with it.
+	if err := ucase.transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if out, changed, err = ucase.update(ctx, u, in); err != nil || !changed {
+			return err
+		}
+
+		return ucase.keep(ctx, out)
+	}); err != nil {
+		return false, err
+	}
+
+	if changed {
+		ucase.notify(out)
+	}
+
+	return changed, nil
+}
+
+// update stores topic content and reports whether it was changed.
+func (ucase *topicUseCase) update(ctx context.Context, u *url.URL, in domain.Topic) (domain.Topic, bool, error) {
+	out, changed := in, true
+
+	if err := ucase.topics.Update(ctx, u, func(tx *domain.Topic) (*domain.Topic, error) {
+		changed = tx.Hash != in.Hash || tx.ContentType != in.ContentType
+		if changed {
+			tx.Self = in.Self
+			tx.UpdatedAt = in.UpdatedAt
+			tx.Content = in.Content
+			tx.ContentType = in.ContentType
+			tx.Hash = in.Hash
+			tx.Canonical = in.Canonical
+			// NOTE(toby3d): content may be changed more than once
+			// within the same second, so UpdatedAt cannot tell
+			// versions apart.
+			tx.VersionID++
+			tx.Entries = feed.Record(*tx, tx.Entries)
+		}
+
+		tx.ETag = in.ETag
+		tx.LastModified = in.LastModified
+		tx.Failures = 0
+		tx.PollAt = in.PollAt
+		out = *tx
 
 		return tx, nil
 	}); err != nil {
 		if !errors.Is(err, topic.ErrNotExist) {
-			return false, fmt.Errorf("cannot publish exists topic: %w", err)
+			return out, false, fmt.Errorf("cannot publish exists topic: %w", err)
 		}
 
-		if err = ucase.topics.Create(ctx, resp.Request.URL, domain.Topic{
-			CreatedAt:   now,
-			UpdatedAt:   now,
-			Self:        resp.Request.URL,
-			ContentType: resp.Header.Get(common.HeaderContentType),
-			Content:     content,
-		}); err != nil {
-			return false, fmt.Errorf("cannot publish a new topic: %w", err)
+		out.VersionID = 1
+		out.Entries = feed.Record(out, nil)
+
+		if err = ucase.topics.Create(ctx, out.Self, out); err != nil {
+			return out, false, fmt.Errorf("cannot publish a new topic: %w", err)
 		}
 	}
 
-	return true, nil
+	return out, changed, nil
+}
+
+// keep stores the current content version of topic and prunes the oldest
+// ones, if any.
+func (ucase *topicUseCase) keep(ctx context.Context, t domain.Topic) error {
+	if ucase.versions == nil || ucase.keepVersions == 0 {
+		return nil
+	}
+
+	if err := ucase.versions.Create(ctx, t.Version()); err != nil {
+		return fmt.Errorf("cannot keep topic version: %w", err)
+	}
+
+	if _, err := ucase.versions.Prune(ctx, t.Self, ucase.keepVersions); err != nil {
+		return fmt.Errorf("cannot prune topic versions: %w", err)
+	}
+
+	return nil
+}
+
+// notify wakes up content distribution of published topic without blocking
+// the publisher. Missed notifications will be picked up by the next scheduled
+// distribution anyway.
+func (ucase *topicUseCase) notify(t domain.Topic) {
+	select {
+	case ucase.updates <- t:
+	default:
+	}
 }
@@ -2,15 +2,28 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
 
 	"source.toby3d.me/toby3d/hub/internal/common"
 	"source.toby3d.me/toby3d/hub/internal/domain"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
+	subscriptionpkg "source.toby3d.me/toby3d/hub/internal/subscription"
+	subscriptionmemoryrepo "source.toby3d.me/toby3d/hub/internal/subscription/repository/memory"
+	topicpkg "source.toby3d.me/toby3d/hub/internal/topic"
 	topicmemoryrepo "source.toby3d.me/toby3d/hub/internal/topic/repository/memory"
+	topicsqliterepo "source.toby3d.me/toby3d/hub/internal/topic/repository/sqlite"
 	"source.toby3d.me/toby3d/hub/internal/topic/usecase"
 )
 
@@ -27,8 +40,10 @@ func TestTopicUseCase_Publish(t *testing.T) {
 
 	topic.Self, _ = url.Parse(srv.URL + "/")
 
-	ok, err := usecase.NewTopicUseCase(topics, srv.Client()).
-		Publish(context.Background(), topic.Self)
+	ok, err := usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+		Topics: topics,
+		Client: srv.Client(),
+	}).Publish(context.Background(), topic.Self)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -41,3 +56,428 @@ func TestTopicUseCase_Publish(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTopicUseCase_Publish_Gone(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	t.Cleanup(srv.Close)
+
+	topic.Self, _ = url.Parse(srv.URL + "/")
+
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	ucase := usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+		Topics:      topics,
+		Client:      srv.Client(),
+		RetireAfter: 2,
+	})
+
+	for i, want := range []error{topicpkg.ErrStatus, topicpkg.ErrGone} {
+		if _, err := ucase.Publish(context.Background(), topic.Self); !errors.Is(err, want) {
+			t.Errorf("#%d: want %v error, got %v", i, want, err)
+		}
+	}
+}
+
+func TestTopicUseCase_Publish_Moved(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	subscriptions := subscriptionmemoryrepo.NewMemorySubscriptionRepository()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/moved" {
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+
+			return
+		}
+
+		w.Header().Set(common.HeaderContentType, topic.ContentType)
+		fmt.Fprint(w, string(topic.Content))
+	}))
+	t.Cleanup(srv.Close)
+
+	topic.Self, _ = url.Parse(srv.URL + "/")
+	moved, _ := url.Parse(srv.URL + "/moved")
+
+	if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := domain.TestSubscription(t, "https://subscriber.example.com/callback")
+	subscription.Topic = topic.Self
+
+	if err := subscriptions.Create(context.Background(), subscription.SUID(), *subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Client:        srv.Client(),
+	}).Publish(context.Background(), topic.Self); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := topics.Get(context.Background(), topic.Self); !errors.Is(err, topicpkg.ErrNotExist) {
+		t.Errorf("want %v error for old topic, got %v", topicpkg.ErrNotExist, err)
+	}
+
+	actual, err := topics.Get(context.Background(), moved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.Self.String() != moved.String() {
+		t.Errorf("want self %s, got %s", moved, actual.Self)
+	}
+
+	if _, err := subscriptions.Get(context.Background(), subscription.SUID()); !errors.Is(err,
+		subscriptionpkg.ErrNotExist) {
+		t.Errorf("want %v error for old subscription, got %v", subscriptionpkg.ErrNotExist, err)
+	}
+
+	subscription.Topic = moved
+
+	if _, err := subscriptions.Get(context.Background(), subscription.SUID()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTopicUseCase_Publish_NotModified(t *testing.T) {
+	t.Parallel()
+
+	for name, etag := range map[string]string{
+		"etag": `"v1"`,
+		"hash": "",
+	} {
+		name, etag := name, etag
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			topic := domain.TestTopic(t)
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if etag != "" && r.Header.Get(common.HeaderIfNoneMatch) == etag {
+					w.WriteHeader(http.StatusNotModified)
+
+					return
+				}
+
+				w.Header().Set(common.HeaderETag, etag)
+				w.Header().Set(common.HeaderContentType, topic.ContentType)
+				fmt.Fprint(w, string(topic.Content))
+			}))
+			t.Cleanup(srv.Close)
+
+			topic.Self, _ = url.Parse(srv.URL + "/")
+			updates := make(chan domain.Topic, 2)
+			ucase := usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+				Topics:  topics,
+				Client:  srv.Client(),
+				Updates: updates,
+			})
+
+			for i, want := range []bool{true, false} {
+				ok, err := ucase.Publish(context.Background(), topic.Self)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if ok != want {
+					t.Errorf("#%d: want %t, got %t", i, want, ok)
+				}
+			}
+
+			if len(updates) != 1 {
+				t.Errorf("want %d distributed updates, got %d", 1, len(updates))
+			}
+		})
+	}
+}
+
+func TestTopicUseCase_Publish_Schedule(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		header http.Header
+		status int
+		expect time.Duration
+	}{
+		"adaptive": {header: http.Header{}, status: http.StatusOK, expect: time.Minute},
+		"max-age": {
+			header: http.Header{"Cache-Control": {"max-age=7200"}},
+			status: http.StatusOK,
+			expect: 2 * time.Hour,
+		},
+		"capped": {
+			header: http.Header{"Cache-Control": {"max-age=604800"}},
+			status: http.StatusOK,
+			expect: 24 * time.Hour,
+		},
+		"retry-after": {
+			header: http.Header{"Retry-After": {"172800"}},
+			status: http.StatusServiceUnavailable,
+			expect: 48 * time.Hour,
+		},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			topic := domain.TestTopic(t)
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				for k, v := range tc.header {
+					w.Header()[k] = v
+				}
+
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, "updated")
+			}))
+			t.Cleanup(srv.Close)
+
+			topic.Self, _ = url.Parse(srv.URL + "/")
+
+			if err := topics.Create(context.Background(), topic.Self, *topic); err != nil {
+				t.Fatal(err)
+			}
+
+			before := time.Now().UTC().Round(time.Second)
+			_, _ = usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+				Topics:          topics,
+				Client:          srv.Client(),
+				PollInterval:    time.Minute,
+				PollIntervalMax: 24 * time.Hour,
+			}).Publish(context.Background(), topic.Self)
+
+			actual, err := topics.Get(context.Background(), topic.Self)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if delay := actual.PollAt.Sub(before); delay < tc.expect || delay > tc.expect+time.Second {
+				t.Errorf("want next poll in %s, got %s", tc.expect, delay)
+			}
+		})
+	}
+}
+
+func TestTopicUseCase_PublishContent_Strict(t *testing.T) {
+	t.Parallel()
+
+	hub := domain.TestConfig(t).BaseURL
+
+	for name, tc := range map[string]struct {
+		href   string
+		expect error
+	}{
+		"advertised": {href: hub.String(), expect: nil},
+		"missing":    {href: "https://hub.example.net/", expect: topicpkg.ErrHub},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			topic := domain.TestTopic(t)
+			topic.ContentType = "application/atom+xml"
+			topic.Content = []byte(`<feed xmlns="http://www.w3.org/2005/Atom"><id>urn:lipsum</id>` +
+				`<link rel="hub" href="` + tc.href + `"/></feed>`)
+
+			topics := topicmemoryrepo.NewMemoryTopicRepository()
+
+			// NOTE(toby3d): content of direct publishing must
+			// advertise the hub by itself.
+			if _, err := usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+				Topics: topics,
+				Hub:    hub,
+			}).PublishContent(context.Background(), *topic); !errors.Is(err, tc.expect) {
+				t.Errorf("want %v error, got %v", tc.expect, err)
+			}
+
+			if _, err := topics.Get(context.Background(), topic.Self); (err == nil) != (tc.expect == nil) {
+				t.Errorf("want stored topic %t, got error %v", tc.expect == nil, err)
+			}
+		})
+	}
+}
+
+func TestTopicUseCase_PublishContent_Versions(t *testing.T) {
+	t.Parallel()
+
+	topic := domain.TestTopic(t)
+	topics := topicmemoryrepo.NewMemoryTopicRepository()
+	versions := topicmemoryrepo.NewMemoryVersionRepository()
+	ucase := usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+		Topics:       topics,
+		Versions:     versions,
+		KeepVersions: 2,
+	})
+
+	// NOTE(toby3d): all publishes are most likely happen within the same
+	// second, so only version identifiers can tell them apart.
+	for i, content := range []string{"one", "two", "three"} {
+		in := *topic
+		in.Content = []byte(content)
+
+		if _, err := ucase.PublishContent(context.Background(), in); err != nil {
+			t.Fatalf("#%d: %s", i, err)
+		}
+	}
+
+	actual, err := topics.Get(context.Background(), topic.Self)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.VersionID != 3 {
+		t.Errorf("want %d version, got %d", 3, actual.VersionID)
+	}
+
+	kept, err := versions.Fetch(context.Background(), topic.Self)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(kept) != 2 || kept[0].ID != 3 || kept[1].ID != 2 {
+		t.Fatalf("want versions %d and %d, got %+v", 3, 2, kept)
+	}
+
+	if string(kept[1].Content) != "two" || kept[1].Hash != domain.NewContentHash([]byte("two")) {
+		t.Errorf("want %q version content, got %q", "two", kept[1].Content)
+	}
+}
+
+func TestTopicUseCase_PublishContent_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	db := sqlx.MustOpen("sqlite", sqlutil.DSN(filepath.Join(t.TempDir(), "testing.db")))
+	t.Cleanup(func() { _ = db.Close() })
+
+	topics, err := topicsqliterepo.NewSQLiteTopicRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := topicsqliterepo.NewSQLiteVersionRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic := domain.TestTopic(t)
+	if err = topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	const publishes int = 32
+
+	ucase := usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+		Topics:       topics,
+		Versions:     versions,
+		KeepVersions: uint(publishes),
+		Transactor:   sqlutil.NewTransactor(db),
+	})
+
+	wg := new(sync.WaitGroup)
+
+	for i := 0; i < publishes; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			in := *topic
+			in.Content = []byte(strconv.Itoa(i))
+
+			if _, err := ucase.PublishContent(context.Background(), in); err != nil {
+				t.Errorf("#%d: %s", i, err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	actual, err := topics.Get(context.Background(), topic.Self)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE(toby3d): every concurrent content change gets it's own version.
+	if expect := topic.VersionID + uint64(publishes); actual.VersionID != expect {
+		t.Errorf("want %d version, got %d", expect, actual.VersionID)
+	}
+
+	kept, err := versions.Fetch(context.Background(), topic.Self)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(kept) != publishes {
+		t.Errorf("want %d kept versions, got %d", publishes, len(kept))
+	}
+}
+
+// failedVersions fails to keep any content version.
+type failedVersions struct {
+	topicpkg.VersionRepository
+}
+
+var errVersion = errors.New("cannot keep version")
+
+func (failedVersions) Create(_ context.Context, _ domain.TopicVersion) error {
+	return errVersion
+}
+
+func TestTopicUseCase_PublishContent_VersionFailed(t *testing.T) {
+	t.Parallel()
+
+	db := sqlx.MustOpen("sqlite", sqlutil.DSN(filepath.Join(t.TempDir(), "testing.db")))
+	t.Cleanup(func() { _ = db.Close() })
+
+	topics, err := topicsqliterepo.NewSQLiteTopicRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := topicsqliterepo.NewSQLiteVersionRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic := domain.TestTopic(t)
+	if err = topics.Create(context.Background(), topic.Self, *topic); err != nil {
+		t.Fatal(err)
+	}
+
+	in := *topic
+	in.Content = []byte("updated")
+
+	// NOTE(toby3d): topic must not point to the version which is not kept.
+	if _, err = usecase.NewTopicUseCase(usecase.NewTopicUseCaseParams{
+		Topics:       topics,
+		Versions:     failedVersions{VersionRepository: versions},
+		KeepVersions: 1,
+		Transactor:   sqlutil.NewTransactor(db),
+	}).PublishContent(context.Background(), in); !errors.Is(err, errVersion) {
+		t.Fatalf("want %v, got %v", errVersion, err)
+	}
+
+	actual, err := topics.Get(context.Background(), topic.Self)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.VersionID != topic.VersionID || actual.Hash != topic.Hash {
+		t.Errorf("want rolled back version %d, got %d", topic.VersionID, actual.VersionID)
+	}
+}
@@ -0,0 +1,147 @@
+package urlutil
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// defaultPorts contains ports which are implied by URL scheme.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonical returns a copy of u in the canonical form, so equivalent URLs of
+// the same resource become equal strings: scheme and host are lowercased,
+// default port and fragment are dropped, empty path of HTTP URL becomes root,
+// percent-encoding of unreserved characters is decoded and hexadecimal digits
+// of the rest are uppercased. Canonical returns nil for nil u.
+//
+// See: https://www.rfc-editor.org/rfc/rfc3986#section-6.2.2
+func Canonical(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+
+	out := *u
+	if u.User != nil {
+		user := *u.User
+		out.User = &user
+	}
+
+	out.Scheme = strings.ToLower(out.Scheme)
+	out.Host = strings.ToLower(out.Host)
+	out.Fragment, out.RawFragment = "", ""
+
+	if port := out.Port(); port != "" && port == defaultPorts[out.Scheme] {
+		out.Host = strings.TrimSuffix(out.Host, ":"+port)
+	}
+
+	if out.Opaque != "" {
+		return &out
+	}
+
+	if _, ok := defaultPorts[out.Scheme]; ok && out.Host != "" && out.Path == "" {
+		out.Path, out.RawPath = "/", ""
+	}
+
+	rawPath := normalizeEscapes(out.EscapedPath())
+	if path, err := url.PathUnescape(rawPath); err == nil {
+		out.Path, out.RawPath = path, rawPath
+	}
+
+	out.RawQuery = normalizeEscapes(out.RawQuery)
+	out.ForceQuery = false
+
+	return &out
+}
+
+// SortQuery returns a copy of u with query parameters sorted by their keys.
+// Values of the same key keep their order.
+func SortQuery(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+
+	out := *u
+	if out.RawQuery == "" {
+		return &out
+	}
+
+	params := strings.Split(out.RawQuery, "&")
+	sort.SliceStable(params, func(i, j int) bool {
+		ki, _, _ := strings.Cut(params[i], "=")
+		kj, _, _ := strings.Cut(params[j], "=")
+
+		return ki < kj
+	})
+
+	out.RawQuery = strings.Join(params, "&")
+
+	return &out
+}
+
+// CanonicalString returns the canonical form of raw URL, or raw itself if it
+// cannot be parsed.
+func CanonicalString(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	return Canonical(u).String()
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters of s and
+// uppercases hexadecimal digits of the rest percent-encoded octets.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var out strings.Builder
+
+	out.Grow(len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			out.WriteByte(s[i])
+
+			continue
+		}
+
+		if c := unhex(s[i+1])<<4 | unhex(s[i+2]); isUnreserved(c) {
+			out.WriteByte(c)
+		} else {
+			out.WriteString(strings.ToUpper(s[i : i+3]))
+		}
+
+		i += 2
+	}
+
+	return out.String()
+}
+
+// isUnreserved reports whether c is an unreserved URI character.
+//
+// See: https://www.rfc-editor.org/rfc/rfc3986#section-2.3
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
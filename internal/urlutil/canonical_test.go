@@ -0,0 +1,57 @@
+package urlutil_test
+
+import (
+	"net/url"
+	"testing"
+
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+func TestCanonical(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		input, expect string
+	}{
+		"host":     {input: "HTTPS://Example.COM/Feed", expect: "https://example.com/Feed"},
+		"port":     {input: "https://example.com:443/feed", expect: "https://example.com/feed"},
+		"custom":   {input: "http://example.com:8080/feed", expect: "http://example.com:8080/feed"},
+		"fragment": {input: "https://example.com/feed#x", expect: "https://example.com/feed"},
+		"root":     {input: "https://example.com", expect: "https://example.com/"},
+		"escapes": {
+			input:  "https://example.com/%7euser/a%2fb?q=%e2%9c%93",
+			expect: "https://example.com/~user/a%2Fb?q=%E2%9C%93",
+		},
+		"query": {input: "https://example.com/feed?b=2&a=1", expect: "https://example.com/feed?b=2&a=1"},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := url.Parse(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual := urlutil.Canonical(u).String(); actual != tc.expect {
+				t.Errorf("want '%s', got '%s'", tc.expect, actual)
+			}
+
+			if u.String() == tc.expect && tc.input != tc.expect {
+				t.Errorf("input URL must not be changed")
+			}
+		})
+	}
+}
+
+func TestSortQuery(t *testing.T) {
+	t.Parallel()
+
+	u, _ := url.Parse("https://example.com/feed?b=2&a=1&b=1")
+
+	const expect string = "https://example.com/feed?a=1&b=2&b=1"
+	if actual := urlutil.SortQuery(u).String(); actual != expect {
+		t.Errorf("want '%s', got '%s'", expect, actual)
+	}
+}
@@ -0,0 +1,33 @@
+package urlutil
+
+import (
+	"net/url"
+	"strings"
+)
+
+// HasPrefix reports whether u is equal to prefix or is placed under it. Both
+// URLs are compared in the canonical form: scheme, user info and host must be
+// equal, and path must match prefix path on segments boundary, so
+// "https://example.com/blog" prefix contains "https://example.com/blog/post"
+// but not "https://example.com/blog-post". Prefix with query contains only URL
+// equal to it.
+func HasPrefix(u, prefix *url.URL) bool {
+	if u == nil || prefix == nil {
+		return false
+	}
+
+	u, prefix = Canonical(u), Canonical(prefix)
+
+	if u.Scheme != prefix.Scheme || u.User.String() != prefix.User.String() || u.Host != prefix.Host ||
+		u.Opaque != prefix.Opaque {
+		return false
+	}
+
+	if prefix.RawQuery != "" {
+		return u.String() == prefix.String()
+	}
+
+	path, root := u.EscapedPath(), strings.TrimSuffix(prefix.EscapedPath(), "/")
+
+	return path == root || strings.HasPrefix(path, root+"/")
+}
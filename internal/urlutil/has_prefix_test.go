@@ -0,0 +1,50 @@
+package urlutil_test
+
+import (
+	"net/url"
+	"testing"
+
+	"source.toby3d.me/toby3d/hub/internal/urlutil"
+)
+
+func TestHasPrefix(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		input, prefix string
+		expect        bool
+	}{
+		"equal":     {input: "https://example.com/blog", prefix: "https://example.com/blog", expect: true},
+		"child":     {input: "https://example.com/blog/post", prefix: "https://example.com/blog", expect: true},
+		"slash":     {input: "https://example.com/blog/post", prefix: "https://example.com/blog/", expect: true},
+		"root":      {input: "https://example.com/blog", prefix: "https://example.com", expect: true},
+		"canonical": {input: "https://Example.com:443/blog/post", prefix: "https://example.com/blog", expect: true},
+		"segment":   {input: "https://example.com/blog-evil", prefix: "https://example.com/blog"},
+		"host":      {input: "https://example.com.evil/blog", prefix: "https://example.com"},
+		"port":      {input: "https://example.com:8443/blog", prefix: "https://example.com"},
+		"scheme":    {input: "http://example.com/blog", prefix: "https://example.com"},
+		"user":      {input: "https://evil@example.com/blog", prefix: "https://example.com"},
+		"query":     {input: "https://example.com/feed?page=2", prefix: "https://example.com/feed?page=1"},
+		"parent":    {input: "https://example.com/", prefix: "https://example.com/blog"},
+	} {
+		name, tc := name, tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := url.Parse(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prefix, err := url.Parse(tc.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual := urlutil.HasPrefix(u, prefix); actual != tc.expect {
+				t.Errorf("HasPrefix(%s, %s) = %t, want %t", tc.input, tc.prefix, actual, tc.expect)
+			}
+		})
+	}
+}
@@ -7,11 +7,15 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/caarlos0/env/v10"
@@ -20,10 +24,17 @@ import (
 	"golang.org/x/text/message"
 	_ "modernc.org/sqlite"
 
+	adminhttpdelivery "source.toby3d.me/toby3d/hub/internal/admin/delivery/http"
 	"source.toby3d.me/toby3d/hub/internal/domain"
 	hubhttprelivery "source.toby3d.me/toby3d/hub/internal/hub/delivery/http"
 	hubucase "source.toby3d.me/toby3d/hub/internal/hub/usecase"
 	"source.toby3d.me/toby3d/hub/internal/middleware"
+	"source.toby3d.me/toby3d/hub/internal/netutil"
+	"source.toby3d.me/toby3d/hub/internal/policy"
+	publishersqliterepo "source.toby3d.me/toby3d/hub/internal/publisher/repository/sqlite"
+	publisherucase "source.toby3d.me/toby3d/hub/internal/publisher/usecase"
+	queuesqliterepo "source.toby3d.me/toby3d/hub/internal/queue/repository/sqlite"
+	"source.toby3d.me/toby3d/hub/internal/sqlutil"
 	subscriptionsqliterepo "source.toby3d.me/toby3d/hub/internal/subscription/repository/sqlite"
 	subscriptionucase "source.toby3d.me/toby3d/hub/internal/subscription/usecase"
 	topicsqliterepo "source.toby3d.me/toby3d/hub/internal/topic/repository/sqlite"
@@ -37,7 +48,8 @@ var logger = log.New(os.Stdout, "hub\t", log.LstdFlags)
 var static embed.FS
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	static, err := fs.Sub(static, filepath.Join("web"))
 	if err != nil {
@@ -52,7 +64,7 @@ func main() {
 		logger.Fatalln(err)
 	}
 
-	db, err := sqlx.Open("sqlite", config.DB)
+	db, err := sqlx.Open("sqlite", sqlutil.DSN(config.DB))
 	if err != nil {
 		logger.Fatalf("cannot open database on path %s: %s", config.DB, err)
 	}
@@ -67,18 +79,135 @@ func main() {
 		logger.Fatalln(err)
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	versions, err := topicsqliterepo.NewSQLiteVersionRepository(db)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	deliveries, err := subscriptionsqliterepo.NewSQLiteDeliveryRepository(db)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	jobs, err := queuesqliterepo.NewSQLiteQueueRepository(db)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	publishers, err := publishersqliterepo.NewSQLitePublisherRepository(db)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	allow, err := netutil.ParsePrefixes(config.AllowNetworks)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	// NOTE(toby3d): topic and callback URLs are provided by anonymous
+	// users, so all outbound requests must not reach internal networks.
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: netutil.NewDialer(allow...).Transport(),
+	}
+	updates := make(chan domain.Topic, 1)
+
+	// NOTE(toby3d): topics must advertise this hub only in strict mode.
+	var hub *url.URL
+	if config.StrictDiscovery {
+		hub = config.BaseURL
+	}
+
+	rules, err := policy.Load(config)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	subscriptionPolicy := policy.NewEngine(rules)
+
+	// NOTE(toby3d): reload policy without restart.
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+
+		for range hup {
+			rules, err := policy.Load(config)
+			if err != nil {
+				logger.Printf("cannot reload policy: %s", err)
+
+				continue
+			}
+
+			subscriptionPolicy.Store(rules)
+			logger.Println("policy reloaded")
+		}
+	}()
+
+	publisherService := publisherucase.NewPublisherUseCase(publisherucase.NewPublisherUseCaseParams{
+		Publishers: publishers,
+	})
+
 	matcher := language.NewMatcher(message.DefaultCatalog.Languages())
-	topicService := topicucase.NewTopicUseCase(topics, client)
-	subscriptionService := subscriptionucase.NewSubscriptionUseCase(subscriptions, topics, client)
-	hubService := hubucase.NewHubUseCase(topics, subscriptions, client, config.BaseURL)
+	topicService := topicucase.NewTopicUseCase(topicucase.NewTopicUseCaseParams{
+		Topics:          topics,
+		Client:          client,
+		Updates:         updates,
+		RetireAfter:     config.RetireAfter,
+		PollInterval:    config.PollInterval,
+		PollIntervalMax: config.PollIntervalMax,
+		Hub:             hub,
+		Subscriptions:   subscriptions,
+		Queue:           jobs,
+		Transactor:      sqlutil.NewTransactor(db),
+		Versions:        versions,
+		KeepVersions:    config.TopicVersions,
+	})
+	subscriptionService := subscriptionucase.NewSubscriptionUseCase(subscriptionucase.NewSubscriptionUseCaseParams{
+		Subscriptions: subscriptions,
+		Topics:        topics,
+		Client:        client,
+		Hub:           hub,
+	})
+	hubService := hubucase.NewHubUseCase(hubucase.NewHubUseCaseParams{
+		Topics:        topics,
+		Subscriptions: subscriptions,
+		Queue:         jobs,
+		Client:        client,
+		Config:        config,
+		Updates:       updates,
+		Publisher:     topicService,
+		Deliveries:    deliveries,
+		Versions:      versions,
+		Logger:        logger,
+	})
 
 	handler := hubhttprelivery.NewHandler(hubhttprelivery.NewHandlerParams{
-		Hub:           hubService,
-		Subscriptions: subscriptionService,
-		Topics:        topicService,
-		Matcher:       matcher,
-		Name:          config.Name,
+		Hub:                hubService,
+		Subscriptions:      subscriptionService,
+		Topics:             topicService,
+		Matcher:            matcher,
+		Name:               config.Name,
+		VerifyAttempts:     config.VerifyAttempts,
+		VerifyTimeout:      config.VerifyTimeout,
+		VerifyBackoff:      config.VerifyBackoff,
+		VerifyWorkers:      config.VerifyWorkers,
+		LeaseMin:           config.LeaseMin,
+		LeaseMax:           config.LeaseMax,
+		LeaseDefault:       config.LeaseDefault,
+		PublishToken:       config.PublishToken,
+		PublishPrefixLimit: config.PublishPrefixLimit,
+		SortQuery:          config.SortQuery,
+		Policy:             subscriptionPolicy,
+		Publishers:         publisherService,
+		PublishAuth:        config.PublishAuth,
+	})
+
+	admin := adminhttpdelivery.NewHandler(adminhttpdelivery.NewHandlerParams{
+		Hub:        hubService,
+		Topics:     topicService,
+		Publishers: publisherService,
+		Token:      config.AdminToken,
+		SortQuery:  config.SortQuery,
 	})
 
 	server := &http.Server{
@@ -89,6 +218,8 @@ func main() {
 			switch head {
 			case "":
 				handler.ServeHTTP(w, r)
+			case "admin":
+				admin.ServeHTTP(w, r)
 			case "static":
 				http.FileServer(http.FS(static)).ServeHTTP(w, r)
 			}
@@ -98,10 +229,41 @@ func main() {
 		ErrorLog:     logger,
 	}
 
-	go hubService.ListenAndServe(ctx)
+	go func() {
+		// NOTE(toby3d): queued deliveries survive in database, so
+		// distribution continues after restart of the stopped loop.
+		for {
+			err := hubService.ListenAndServe(ctx)
+			if ctx.Err() != nil {
+				return
+			}
 
-	logger.Printf("started %s on %s: %s", config.Name, config.Bind, config.BaseURL.String())
-	if err = server.ListenAndServe(); err != nil {
-		logger.Fatalln(err)
+			logger.Printf("distribution stopped, restarting: %s", err)
+			time.Sleep(time.Second)
+		}
+	}()
+
+	go func() {
+		logger.Printf("started %s on %s: %s", config.Name, config.Bind, config.BaseURL.String())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatalln(err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	// NOTE(toby3d): pending verifications of intent are canceled, their
+	// subscribers may retry requests after restart.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		logger.Println(err)
 	}
+
+	if err = handler.Shutdown(shutdownCtx); err != nil {
+		logger.Println(err)
+	}
+
+	logger.Println("stopped")
 }